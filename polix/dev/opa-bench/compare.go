@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// loadResults reads a results file written with -format=json.
+func loadResults(path string) (ResultsOutput, error) {
+	var data ResultsOutput
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return data, fmt.Errorf("reading baseline: %w", err)
+	}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return data, fmt.Errorf("parsing baseline %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// schemaMajor returns the major version of a results file's
+// SchemaVersion. Files without one predate the field and share the
+// shape of major version 1.
+func schemaMajor(version string) string {
+	if version == "" {
+		return "1"
+	}
+	major, _, _ := strings.Cut(version, ".")
+	return major
+}
+
+// checkSchema returns an error unless the results file at path, holding
+// data, has the major schemaVersion of the running binary, so results of
+// another shape are never compared.
+func checkSchema(path string, data ResultsOutput) error {
+	if schemaMajor(data.SchemaVersion) == schemaMajor(schemaVersion) {
+		return nil
+	}
+	return fmt.Errorf("baseline %s has results schema version %s, incompatible with version %s written by this opa-bench; re-run the baseline with this version",
+		path, data.SchemaVersion, schemaVersion)
+}
+
+// resultValue returns the numeric result key of b. Results read back from
+// JSON hold float64 where freshly measured ones hold int64.
+func resultValue(b BenchmarkResult, key string) (float64, bool) {
+	switch v := b.Results[key].(type) {
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// significanceLevel is the p-value below which a shift between two sample
+// distributions is treated as real.
+const significanceLevel = 0.05
+
+// mannWhitneyU returns the Mann-Whitney U statistic of a against b and
+// its two-sided p-value, using the normal approximation with tie and
+// continuity corrections. The approximation is sound for the sample counts
+// the runner collects (at least 10 per side).
+func mannWhitneyU(a, b []float64) (u, p float64) {
+	type obs struct {
+		value float64
+		fromA bool
+	}
+	all := make([]obs, 0, len(a)+len(b))
+	for _, v := range a {
+		all = append(all, obs{v, true})
+	}
+	for _, v := range b {
+		all = append(all, obs{v, false})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].value < all[j].value })
+
+	// Tied values share the average of their ranks.
+	rankSumA, tieTerm := 0.0, 0.0
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].value == all[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankSumA += rank
+			}
+		}
+		t := float64(j - i)
+		tieTerm += t*t*t - t
+		i = j
+	}
+
+	n1, n2 := float64(len(a)), float64(len(b))
+	n := n1 + n2
+	u = rankSumA - n1*(n1+1)/2
+	mu := n1 * n2 / 2
+	sigma := math.Sqrt(n1 * n2 / 12 * ((n + 1) - tieTerm/(n*(n-1))))
+	if sigma == 0 {
+		return u, 1
+	}
+	z := math.Max(math.Abs(u-mu)-0.5, 0) / sigma
+	return u, math.Erfc(z / math.Sqrt2)
+}
+
+// comparison is the change of one benchmark between a baseline and the
+// current run. A benchmark missing from either run has only the side it
+// appears in. When both runs were made with -raw, the comparison also
+// carries the shift in median and a Mann-Whitney p-value.
+type comparison struct {
+	name           string
+	baseline       float64
+	current        float64
+	inBaseline     bool
+	inCurrent      bool
+	deltaPct       float64
+	tested         bool
+	medianDeltaPct float64
+	pValue         float64
+	regressed      bool
+}
+
+// compareResults matches current against baseline by name, in the order
+// of current followed by benchmarks only in the baseline.
+//
+// When both runs were made with -raw, a benchmark regressed when the
+// Mann-Whitney test finds the distributions differ (p below
+// significanceLevel) and its median grew by more than thresholdPct
+// percent; a noisy benchmark whose mean drifted is not flagged. Otherwise
+// it regressed when its mean-ns grew by more than thresholdPct percent.
+func compareResults(baseline, current []BenchmarkResult, thresholdPct float64) []comparison {
+	base := make(map[string]BenchmarkResult, len(baseline))
+	for _, b := range baseline {
+		base[b.Name] = b
+	}
+
+	var rows []comparison
+	seen := make(map[string]bool, len(current))
+	for _, c := range current {
+		seen[c.Name] = true
+		row := comparison{name: c.Name, inCurrent: true}
+		row.current, _ = resultValue(c, "mean-ns")
+		b, ok := base[c.Name]
+		if ok {
+			row.baseline, row.inBaseline = resultValue(b, "mean-ns")
+		}
+		if row.inBaseline && row.baseline > 0 {
+			row.deltaPct = (row.current - row.baseline) / row.baseline * 100
+			row.regressed = row.deltaPct > thresholdPct
+		}
+		if ok && len(b.Samples) > 0 && len(c.Samples) > 0 {
+			baseMedian := median(sortedCopy(b.Samples))
+			row.tested = true
+			row.medianDeltaPct = (median(sortedCopy(c.Samples)) - baseMedian) / baseMedian * 100
+			_, row.pValue = mannWhitneyU(b.Samples, c.Samples)
+			row.regressed = row.pValue < significanceLevel && row.medianDeltaPct > thresholdPct
+		}
+		rows = append(rows, row)
+	}
+	for _, b := range baseline {
+		if !seen[b.Name] {
+			row := comparison{name: b.Name, inBaseline: true}
+			row.baseline, _ = resultValue(b, "mean-ns")
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// printComparison prints rows as a table and returns how many regressed.
+func printComparison(w io.Writer, rows []comparison, thresholdPct float64) int {
+	regressions := 0
+	fmt.Fprintf(w, "  %-45s %12s %12s %9s %9s %8s\n",
+		"Benchmark", "Baseline ns", "Current ns", "Mean", "Median", "p-value")
+	for _, r := range rows {
+		switch {
+		case !r.inBaseline:
+			fmt.Fprintf(w, "  %-45s %12s %12.0f %9s\n", r.name, "-", r.current, "new")
+		case !r.inCurrent:
+			fmt.Fprintf(w, "  %-45s %12.0f %12s %9s\n", r.name, r.baseline, "-", "removed")
+		default:
+			medianDelta, pValue := "-", "-"
+			if r.tested {
+				medianDelta = fmt.Sprintf("%+.1f%%", r.medianDeltaPct)
+				pValue = fmt.Sprintf("%.4f", r.pValue)
+			}
+			marker := ""
+			if r.regressed {
+				marker = "  REGRESSION"
+				regressions++
+			}
+			fmt.Fprintf(w, "  %-45s %12.0f %12.0f %+8.1f%% %9s %8s%s\n",
+				r.name, r.baseline, r.current, r.deltaPct, medianDelta, pValue, marker)
+		}
+	}
+	if regressions > 0 {
+		fmt.Fprintf(w, "\n%d benchmark(s) regressed by more than %.1f%%\n", regressions, thresholdPct)
+	}
+	return regressions
+}
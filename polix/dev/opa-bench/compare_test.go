@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestMannWhitneyU(t *testing.T) {
+	a := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	shifted := []float64{11, 12, 13, 14, 15, 16, 17, 18, 19, 20}
+
+	if u, p := mannWhitneyU(a, a); u != 50 || p != 1 {
+		t.Errorf("identical samples: U = %v, p = %v, want 50 and 1", u, p)
+	}
+	if u, p := mannWhitneyU(a, shifted); u != 0 || p >= 0.001 {
+		t.Errorf("disjoint samples: U = %v, p = %v, want 0 and p < 0.001", u, p)
+	}
+	if _, p := mannWhitneyU([]float64{5, 5, 5}, []float64{5, 5, 5}); p != 1 {
+		t.Errorf("all ties: p = %v, want 1", p)
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	for _, c := range []struct {
+		version string
+		ok      bool
+	}{
+		{"", false},
+		{schemaVersion, true},
+		{"2.3.0", true},
+		{"1.0.0", false},
+		{"3.0.0", false},
+		{"0.9", false},
+	} {
+		err := checkSchema("results.json", ResultsOutput{SchemaVersion: c.version})
+		if (err == nil) != c.ok {
+			t.Errorf("checkSchema(%q) = %v, want ok %v", c.version, err, c.ok)
+		}
+	}
+}
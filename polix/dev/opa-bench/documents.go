@@ -1,5 +1,11 @@
 package main
 
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
 var docSimpleSatisfied = map[string]interface{}{
 	"role":   "admin",
 	"level":  10,
@@ -65,6 +71,20 @@ func makeUsersWithOneInactive(n int) []map[string]interface{} {
 	return users
 }
 
+// makeUsersFailingAt returns n active users with verified profiles,
+// except the one at index i, which is inactive and unverified, so every
+// quantifier over the users exits at i.
+func makeUsersFailingAt(n int, i int) []map[string]interface{} {
+	users := makeUsers(n, true)
+	users[i] = map[string]interface{}{
+		"active":  false,
+		"role":    "user",
+		"score":   80,
+		"profile": map[string]interface{}{"verified": false},
+	}
+	return users
+}
+
 func makeUsersWithAdmin(n int, adminIndex int) []map[string]interface{} {
 	users := make([]map[string]interface{}, n)
 	for i := 0; i < n; i++ {
@@ -125,6 +145,22 @@ var docUsers100AllActive = map[string]interface{}{
 	"users": makeUsers(100, true),
 }
 
+var docUsers20FirstUnverified = map[string]interface{}{
+	"users": makeUsersFailingAt(20, 0),
+}
+
+var docUsers20LastUnverified = map[string]interface{}{
+	"users": makeUsersFailingAt(20, 19),
+}
+
+var docUsers100FirstInactive = map[string]interface{}{
+	"users": makeUsersFailingAt(100, 0),
+}
+
+var docUsers100LastInactive = map[string]interface{}{
+	"users": makeUsersFailingAt(100, 99),
+}
+
 var docUsers5FirstAdmin = map[string]interface{}{
 	"users": makeUsersWithAdmin(5, 0),
 }
@@ -141,6 +177,10 @@ var docUsers100LastAdmin = map[string]interface{}{
 	"users": makeUsersWithAdmin(100, 99),
 }
 
+var docUsers100NoAdmin = map[string]interface{}{
+	"users": makeUsersWithAdmin(100, -1),
+}
+
 var docTeamsAllHaveLead = map[string]interface{}{
 	"teams": makeTeams(5, true),
 }
@@ -149,6 +189,96 @@ var docTeamsOneMissingLead = map[string]interface{}{
 	"teams": makeTeamsOneMissingLead(5),
 }
 
+// makeRandomUsers returns n users with randomized active, role, score,
+// and profile.verified fields drawn from rng. About three quarters of the
+// users are active and nine in ten verified; one in ten is an admin.
+func makeRandomUsers(n int, rng *rand.Rand) []map[string]interface{} {
+	roles := []string{"user", "user", "user", "user", "user", "user", "guest", "guest", "guest", "admin"}
+	users := make([]map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		users[i] = map[string]interface{}{
+			"active":  rng.Intn(4) != 0,
+			"role":    roles[rng.Intn(len(roles))],
+			"score":   rng.Intn(101),
+			"profile": map[string]interface{}{"verified": rng.Intn(10) != 0},
+		}
+	}
+	return users
+}
+
+// Shape documents
+
+// makeNestedDoc returns {"a": {"b": {... {"value": true}}}} with value
+// nested under depth keys. Keys run a to z and then start over, matching
+// the paths in nested.rego.
+func makeNestedDoc(depth int) map[string]interface{} {
+	doc := map[string]interface{}{"value": true}
+	for i := depth - 1; i >= 0; i-- {
+		doc = map[string]interface{}{string(rune('a' + i%26)): doc}
+	}
+	return doc
+}
+
+// makeWideDoc returns {"field0": 0, "field1": 1, ...} with keys
+// top-level keys.
+func makeWideDoc(keys int) map[string]interface{} {
+	doc := make(map[string]interface{}, keys)
+	for i := 0; i < keys; i++ {
+		doc[fmt.Sprintf("field%d", i)] = i
+	}
+	return doc
+}
+
+// makeAPIPath returns an API path under /api/v1/users with segments
+// path segments, such as /api/v1/users/id-0/id-1. When valid is false the
+// last segment ends in a character regex.rego does not allow, so the
+// match fails only after scanning the whole path.
+func makeAPIPath(segments int, valid bool) string {
+	var sb strings.Builder
+	sb.WriteString("/api/v1/users")
+	for i := 0; i < segments; i++ {
+		fmt.Fprintf(&sb, "/id-%d", i)
+	}
+	if !valid {
+		sb.WriteString("!")
+	}
+	return sb.String()
+}
+
+// Data documents
+
+// directorySize is the number of users in dataDirectory.
+const directorySize = 10000
+
+// makeDirectory returns a directory of n users keyed by id, user0 to
+// user<n-1>, with every tenth user inactive, and a reader grant for each
+// user in id order, as read by data_lookup.rego.
+func makeDirectory(n int) map[string]interface{} {
+	users := make(map[string]interface{}, n)
+	grants := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("user%d", i)
+		users[id] = map[string]interface{}{"active": i%10 != 0}
+		grants[i] = map[string]interface{}{"user": id, "role": "reader"}
+	}
+	return map[string]interface{}{"users": users, "grants": grants}
+}
+
+// dataDirectory is the data document of the opa/data benchmarks.
+var dataDirectory = map[string]interface{}{
+	"directory": makeDirectory(directorySize),
+}
+
+// makeAllowlist returns the data document of membership.rego with n
+// allowed users, user0 to user<n-1> in that order.
+func makeAllowlist(n int) map[string]interface{} {
+	users := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		users[i] = fmt.Sprintf("user%d", i)
+	}
+	return map[string]interface{}{"allowed_users": users}
+}
+
 // Count and filtered binding documents
 
 func makeUsersWithActiveAndProfile(n int, active bool, verified bool, role string, score int) []map[string]interface{} {
@@ -205,6 +335,27 @@ var docUsers5AllActiveVerified = map[string]interface{}{
 	"users": makeUsersWithActiveAndProfile(5, true, true, "user", 90),
 }
 
+// 5 active users, last one unverified
+var docUsers5ActiveOneUnverified = map[string]interface{}{
+	"users": append(
+		makeUsersWithActiveAndProfile(4, true, true, "user", 90),
+		makeUsersWithActiveAndProfile(1, true, false, "user", 90)...,
+	),
+}
+
+// 5 users: 2 active verified, 3 inactive
+var docUsers5MostlyInactive = map[string]interface{}{
+	"users": append(
+		makeUsersWithActiveAndProfile(2, true, true, "user", 90),
+		makeUsersWithActiveAndProfile(3, false, false, "user", 50)...,
+	),
+}
+
+// 100 active users, none scoring above 80
+var docUsers100ActiveLowScore = map[string]interface{}{
+	"users": makeUsersWithActiveAndProfile(100, true, true, "user", 50),
+}
+
 // 5 users: 3 active verified, 2 inactive
 var docUsers5MixedActive = map[string]interface{}{
 	"users": append(
@@ -278,6 +429,20 @@ var docOrgWithMembers = map[string]interface{}{
 	"active": true,
 }
 
+// Organization with fewer than 5 members
+var docOrgFewMembers = map[string]interface{}{
+	"org": map[string]interface{}{
+		"name": "Acme",
+		"members": []map[string]interface{}{
+			{"name": "User1", "level": 5},
+			{"name": "User2", "level": 5},
+			{"name": "User3", "level": 5},
+			{"name": "User4", "level": 5},
+		},
+	},
+	"active": true,
+}
+
 // 5 active teams with high-level leads
 var docTeams5ActiveWithLeads = map[string]interface{}{
 	"teams": makeActiveTeamsWithLevels(5, true),
@@ -2,7 +2,11 @@ module opa-bench
 
 go 1.25
 
-require github.com/open-policy-agent/opa v1.4.2
+require (
+	github.com/mattn/go-sqlite3 v1.14.33
+	github.com/open-policy-agent/opa v1.4.2
+	golang.org/x/sys v0.31.0
+)
 
 require (
 	github.com/agnivade/levenshtein v1.2.1 // indirect
@@ -30,7 +34,6 @@ require (
 	go.opentelemetry.io/otel/metric v1.35.0 // indirect
 	go.opentelemetry.io/otel/sdk v1.35.0 // indirect
 	go.opentelemetry.io/otel/trace v1.35.0 // indirect
-	golang.org/x/sys v0.31.0 // indirect
 	google.golang.org/protobuf v1.36.6 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 	sigs.k8s.io/yaml v1.4.0 // indirect
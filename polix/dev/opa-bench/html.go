@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+)
+
+// HTML report geometry, in SVG user units.
+const (
+	htmlLabelWidth = 340
+	htmlChartWidth = 480
+	htmlBarHeight  = 18
+	htmlRowHeight  = 24
+)
+
+// htmlBar is one benchmark of an HTML chart, laid out for the template.
+type htmlBar struct {
+	Name              string
+	Label             string
+	Y, TextY, MidY    int
+	Width             float64
+	ErrLow, ErrHigh   float64
+	ErrTop, ErrBottom int
+}
+
+// htmlChart is the bar chart of one category.
+type htmlChart struct {
+	Category string
+	Width    int
+	Height   int
+	Bars     []htmlBar
+}
+
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>OPA benchmark results</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+h2 { font-size: 1.1em; margin-top: 2em; }
+.meta { color: #666; }
+svg text { font-size: 12px; }
+.bar { fill: #4c78a8; }
+.err { stroke: #222; stroke-width: 1; }
+</style>
+</head>
+<body>
+<h1>OPA benchmark results</h1>
+<p class="meta">{{.Timestamp}} &middot; OPA {{.Metadata.OPAVersion}} &middot; {{.Metadata.GoVersion}} {{.Metadata.GOOS}}/{{.Metadata.GOARCH}}{{with .Metadata.CPUModel}} &middot; {{.}}{{end}}</p>
+<p class="meta">Bars show mean evaluation time; whiskers span one standard deviation either side.</p>
+{{range .Charts}}
+<h2>{{.Category}}</h2>
+<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}" role="img" aria-label="{{.Category}} mean evaluation time">
+{{- range .Bars}}
+<g><title>{{.Name}}: {{.Label}}</title>
+<text x="{{$.LabelWidth}}" dx="-8" y="{{.TextY}}" text-anchor="end">{{.Name}}</text>
+<rect class="bar" x="{{$.LabelWidth}}" y="{{.Y}}" width="{{printf "%.1f" .Width}}" height="{{$.BarHeight}}"></rect>
+<line class="err" x1="{{printf "%.1f" .ErrLow}}" x2="{{printf "%.1f" .ErrHigh}}" y1="{{.MidY}}" y2="{{.MidY}}"></line>
+<line class="err" x1="{{printf "%.1f" .ErrLow}}" x2="{{printf "%.1f" .ErrLow}}" y1="{{.ErrTop}}" y2="{{.ErrBottom}}"></line>
+<line class="err" x1="{{printf "%.1f" .ErrHigh}}" x2="{{printf "%.1f" .ErrHigh}}" y1="{{.ErrTop}}" y2="{{.ErrBottom}}"></line>
+<text x="{{printf "%.1f" .ErrHigh}}" dx="6" y="{{.TextY}}">{{.Label}}</text>
+</g>
+{{- end}}
+</svg>
+{{end}}
+</body>
+</html>
+`))
+
+// htmlCharts lays out a bar chart per category of the measured results,
+// in the order the categories first appear. Each chart is scaled to its
+// largest mean plus standard deviation.
+func htmlCharts(results []BenchmarkResult) []htmlChart {
+	var charts []htmlChart
+	index := make(map[string]int)
+	scale := make(map[string]float64)
+	for _, b := range results {
+		if synthetic(b) || !measured(b) {
+			continue
+		}
+		c := category(b.Name)
+		if _, ok := index[c]; !ok {
+			index[c] = len(charts)
+			charts = append(charts, htmlChart{Category: c})
+		}
+		m, _ := resultValue(b, "mean-ns")
+		sd, _ := resultValue(b, "std-dev")
+		scale[c] = math.Max(scale[c], m+sd)
+	}
+
+	// Leave room right of the longest whisker for its label.
+	const plotWidth = htmlChartWidth - 110
+	for _, b := range results {
+		if synthetic(b) || !measured(b) {
+			continue
+		}
+		c := category(b.Name)
+		chart := &charts[index[c]]
+		m, _ := resultValue(b, "mean-ns")
+		sd, _ := resultValue(b, "std-dev")
+		px := plotWidth / scale[c]
+		y := len(chart.Bars)*htmlRowHeight + 4
+		chart.Bars = append(chart.Bars, htmlBar{
+			Name:      b.Name,
+			Label:     fmt.Sprintf("%.0f ns", m),
+			Y:         y,
+			TextY:     y + htmlBarHeight - 5,
+			MidY:      y + htmlBarHeight/2,
+			Width:     m * px,
+			ErrLow:    htmlLabelWidth + math.Max(m-sd, 0)*px,
+			ErrHigh:   htmlLabelWidth + (m+sd)*px,
+			ErrTop:    y + 4,
+			ErrBottom: y + htmlBarHeight - 4,
+		})
+	}
+	for i := range charts {
+		charts[i].Width = htmlLabelWidth + htmlChartWidth
+		charts[i].Height = len(charts[i].Bars)*htmlRowHeight + 8
+	}
+	return charts
+}
+
+// writeHTML renders the results as a standalone HTML page with an inline
+// SVG bar chart per category, needing no scripts or network access.
+func writeHTML(w io.Writer, data ResultsOutput) error {
+	return htmlReport.Execute(w, struct {
+		ResultsOutput
+		Charts     []htmlChart
+		LabelWidth int
+		BarHeight  int
+	}{data, htmlCharts(data.Benchmarks), htmlLabelWidth, htmlBarHeight})
+}
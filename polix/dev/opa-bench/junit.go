@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// junitSuite is a <testsuite> in the common JUnit XML schema read by
+// Jenkins and GitLab.
+type junitSuite struct {
+	XMLName    xml.Name        `xml:"testsuite"`
+	Name       string          `xml:"name,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Hostname   string          `xml:"hostname,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitCase     `xml:"testcase"`
+	SystemOut  string          `xml:"system-out"`
+	SystemErr  string          `xml:"system-err"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure,omitempty"`
+	Error     *junitProblem `xml:"error,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+// junitSeconds renders nanoseconds as the seconds of a JUnit time
+// attribute.
+func junitSeconds(ns float64) string {
+	return strconv.FormatFloat(ns/1e9, 'f', 9, 64)
+}
+
+// writeJUnit renders the results as a JUnit test suite with one test case
+// per benchmark, timed by its mean-ns. With -baseline, a benchmark that
+// regressed by more than -regression-threshold is a failure; one that
+// failed to evaluate is an error and one that was stopped early is
+// skipped.
+func writeJUnit(w io.Writer, data ResultsOutput) error {
+	regressed := make(map[string]comparison)
+	for _, row := range data.comparisons {
+		if row.regressed {
+			regressed[row.name] = row
+		}
+	}
+
+	suite := junitSuite{
+		Name:      "opa-bench",
+		Timestamp: data.Timestamp,
+		Properties: []junitProperty{
+			{"go-version", data.Metadata.GoVersion},
+			{"goos", data.Metadata.GOOS},
+			{"goarch", data.Metadata.GOARCH},
+			{"opa-version", data.Metadata.OPAVersion},
+		},
+	}
+	// The schema's timestamp has no zone; results are timed in UTC.
+	if t, err := time.Parse(time.RFC3339Nano, data.Timestamp); err == nil {
+		suite.Timestamp = t.UTC().Format("2006-01-02T15:04:05")
+	}
+	suite.Hostname, _ = os.Hostname()
+
+	total := 0.0
+	for _, b := range data.Benchmarks {
+		if synthetic(b) {
+			continue
+		}
+		mean, _ := resultValue(b, "mean-ns")
+		c := junitCase{ClassName: "opa-bench", Name: b.Name, Time: junitSeconds(mean)}
+		switch {
+		case b.Error != "":
+			c.Error = &junitProblem{Message: b.Error, Type: "EvalError"}
+			suite.Errors++
+		case !measured(b):
+			c.Skipped = &junitSkipped{Message: "stopped before sampling finished"}
+			suite.Skipped++
+		default:
+			if row, ok := regressed[b.Name]; ok {
+				msg := fmt.Sprintf("mean-ns changed %+.1f%% from baseline %.0f to %.0f",
+					row.deltaPct, row.baseline, row.current)
+				if row.tested {
+					msg = fmt.Sprintf("median-ns changed %+.1f%% from baseline (p=%.4f)",
+						row.medianDeltaPct, row.pValue)
+				}
+				c.Failure = &junitProblem{
+					Message: fmt.Sprintf("%s; threshold %.1f%%", msg, data.regressionThreshold),
+					Type:    "Regression",
+				}
+				suite.Failures++
+			}
+		}
+		total += mean
+		suite.Cases = append(suite.Cases, c)
+	}
+	suite.Tests = len(suite.Cases)
+	suite.Time = junitSeconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suite); err != nil {
+		return fmt.Errorf("marshaling JUnit XML: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
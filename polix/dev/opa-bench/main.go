@@ -1,56 +1,561 @@
 package main
 
 import (
-	"encoding/json"
+	"bytes"
+	"context"
 	"flag"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"os/signal"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/open-policy-agent/opa/v1/version"
 )
 
+// minSamples is the fewest samples the percentile and outlier math can
+// work with.
+const minSamples = 10
+
+// Metadata describes the machine and build a results file came from, so
+// numbers from different runs can be compared.
+type Metadata struct {
+	GoVersion  string `json:"go-version"`
+	GOOS       string `json:"goos"`
+	GOARCH     string `json:"goarch"`
+	NumCPU     int    `json:"num-cpu"`
+	GOMAXPROCS int    `json:"gomaxprocs"`
+	CPUModel   string `json:"cpu-model,omitempty"`
+	OPAVersion string `json:"opa-version"`
+	// Seed generated the documents of the opa/random benchmarks.
+	Seed int64 `json:"seed"`
+	// Drift is the mean-ns of the first benchmark re-run at the end of
+	// the suite divided by its mean-ns at the start; far from 1 means the
+	// machine was not in a steady state.
+	Drift float64 `json:"drift,omitempty"`
+}
+
+func collectMetadata(seed int64) Metadata {
+	return Metadata{
+		GoVersion:  runtime.Version(),
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		CPUModel:   cpuModel(),
+		OPAVersion: version.Version,
+		Seed:       seed,
+	}
+}
+
+// cpuModel returns the CPU model name from /proc/cpuinfo, or "" where that
+// is unavailable.
+func cpuModel() string {
+	data, err := os.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && strings.TrimSpace(key) == "model name" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// schemaVersion is the version of the ResultsOutput JSON shape. Bump the
+// minor version when adding a field and the major version when renaming,
+// retyping or removing one, or when benchmarks keep their names but no
+// longer measure the same thing; -baseline refuses files of another major
+// version. 2.0.0 marks the default rules added to policies/*.rego.
+const schemaVersion = "2.0.0"
+
 type ResultsOutput struct {
-	Timestamp  string            `json:"timestamp"`
-	Engine     string            `json:"engine"`
-	Benchmarks []BenchmarkResult `json:"benchmarks"`
+	// SchemaVersion is the schemaVersion the file was written with; files
+	// from before it was introduced have none.
+	SchemaVersion    string            `json:"schema-version,omitempty"`
+	Timestamp        string            `json:"timestamp"`
+	Engine           string            `json:"engine"`
+	Metadata         Metadata          `json:"metadata"`
+	WarmupIterations int               `json:"warmup-iterations"`
+	SampleIterations int               `json:"sample-iterations"`
+	Count            int               `json:"count"`
+	Benchmarks       []BenchmarkResult `json:"benchmarks"`
+	// Speedups compares OPA with polix when run with -polix.
+	Speedups []EngineSpeedup `json:"engine-speedups,omitempty"`
+
+	// comparisons against -baseline, for formats that report regressions.
+	comparisons         []comparison
+	regressionThreshold float64
+}
+
+// parseSizes parses the comma-separated collection sizes of -sizes.
+func parseSizes(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var sizes []int
+	for _, field := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid size %q; expected a positive integer", field)
+		}
+		sizes = append(sizes, n)
+	}
+	return sizes, nil
 }
 
 func main() {
-	output := flag.String("output", "opa-benchmark-results.json", "Output JSON file")
+	output := flag.String("output", "", "Output file (default opa-benchmark-results.<format extension>); - writes to stdout")
+	format := flag.String("format", "json", "Output format: "+formatNames()+"; junit marks benchmarks that regressed against -baseline as failures")
+	cvThreshold := flag.Float64("cv-threshold", 0.15, "Mark benchmarks whose coefficient of variation exceeds this as unstable")
+	trim := flag.Bool("trim-outliers", false, "Discard samples outside 1.5×IQR before computing statistics")
+	filter := flag.String("filter", "", "Only run benchmarks whose name matches this regexp")
+	dbPath := flag.String("db", "", "With -format=sqlite, the database to add the run to (default opa-benchmark-results.db); build with -tags opa_sqlite")
+	only := flag.String("only", "", "Only run the benchmark with exactly this name, such as opa/complex-satisfied")
+	warmup := flag.Int("warmup", 100, "Warmup evaluations per benchmark before sampling")
+	samples := flag.Int("samples", 1000, "Timed evaluations per benchmark")
+	var baselinePaths baselineFlag
+	flag.Var(&baselinePaths, "baseline", "Compare mean-ns against this earlier JSON results file; repeat it or pass a glob to also print the trend of each benchmark across the files in timestamp order, comparing against the latest")
+	threshold := flag.Float64("regression-threshold", 10, "Percent increase over -baseline that fails the run: in median-ns when both runs used -raw, else in mean-ns")
+	raw := flag.Bool("raw", false, "Include the raw samples of each benchmark in the JSON output, for offline reanalysis and significance tests against -baseline (adds about 8 bytes per sample)")
+	count := flag.Int("count", 1, "Run the whole suite this many times and aggregate the results")
+	policyDir := flag.String("policy-dir", "", "Benchmark the .rego files in this directory instead of the embedded suite; each <name>.rego is queried as data.policy.<name>.allow, or with the query in <name>.query, with input from <name>.json if present")
+	inputPath := flag.String("input", "", "With -policy-dir, run every policy against this JSON input document; each policy still gets its own benchmark, named opa/<policy>/<input>, so a directory of several policies yields several results")
+	wasm := flag.Bool("wasm", false, "Also benchmark the simple, medium, and complex policies compiled to WASM (requires building with -tags opa_wasm)")
+	parallel := flag.Int("parallel", 1, "Run this many embedded benchmarks at once on worker goroutines, each pinned to its own CPU core; results are sorted by name (1 runs them one after another)")
+	prepare := flag.Bool("prepare", false, "Also benchmark preparing each embedded policy from scratch, the compilation paid on every policy update, as opa/prepare/*")
+	timeout := flag.Duration("timeout", 0, "Abandon a benchmark that runs longer than this and mark it timed-out (0 disables)")
+	auto := flag.Bool("auto", false, "Keep sampling in batches of -samples until the mean changes by less than -auto-threshold between batches and its 95% confidence margin is within -auto-threshold")
+	autoThreshold := flag.Float64("auto-threshold", 0.01, "With -auto, the relative change in mean between batches at which sampling stops")
+	maxSamples := flag.Int("max-samples", 100000, "With -auto, the most samples to collect per benchmark")
+	sizesFlag := flag.String("sizes", "", "Comma-separated user counts (e.g. 10,50,100,500,1000) to generate extra quantifier and count benchmarks for, for plotting eval time against collection size")
+	seed := flag.Int64("seed", 1, "Seed for the randomized user documents of the opa/random benchmarks; the same seed reproduces the same documents")
+	cpuProfile := flag.String("cpuprofile", "", "Write a CPU profile of the benchmark run to this file; view it with go tool pprof")
+	memProfile := flag.String("memprofile", "", "Write a heap profile to this file after the run; view it with go tool pprof")
+	tracePath := flag.String("trace", "", "Write an execution trace of the benchmark run to this file; view it with go tool trace")
+	polix := flag.Bool("polix", false, "Also run the polix CI benchmarks (requires the clojure CLI) and add their evaluation results under polix/* names for a head-to-head comparison")
+	polixDir := flag.String("polix-dir", "../..", "With -polix, the polix project directory holding deps.edn")
+	quiet := flag.Bool("quiet", false, "Print nothing but errors; only write the results file")
+	verbose := flag.Bool("verbose", false, "Print a histogram of each benchmark's samples as it finishes")
+	pin := flag.Int("pin", -1, "Lock the benchmarks to one OS thread and, on Linux only, pin it to this CPU core; combine with -maxprocs 1 for the steadiest timings (-1 disables)")
+	maxprocs := flag.Int("maxprocs", 0, "Set GOMAXPROCS for the run, limiting the cores the GC and runtime use alongside the benchmarks (0 leaves it untouched)")
+	list := flag.Bool("list", false, "List the benchmark names by category and exit")
+	validate := flag.Bool("validate", false, "Prepare every policy, report any that fail to compile, and exit without benchmarking; usable as a pre-commit check")
 	flag.Parse()
 
-	fmt.Println("OPA Benchmark Runner")
-	fmt.Println("====================")
+	if *inputPath != "" && *policyDir == "" {
+		fmt.Fprintln(os.Stderr, "Error: -input requires -policy-dir")
+		os.Exit(1)
+	}
+
+	if *parallel < 1 {
+		fmt.Fprintf(os.Stderr, "Error: -parallel must be at least 1, got %d\n", *parallel)
+		os.Exit(1)
+	}
+	if *maxprocs < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -maxprocs must not be negative, got %d\n", *maxprocs)
+		os.Exit(1)
+	}
+
+	sizes, err := parseSizes(*sizesFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: -sizes: %v\n", err)
+		os.Exit(1)
+	}
 
-	results, err := runAllBenchmarks()
+	available, err := runOptions{sizes: sizes, seed: *seed, policyDir: *policyDir, inputPath: *inputPath}.available()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	data := ResultsOutput{
-		Timestamp:  time.Now().UTC().Format(time.RFC3339Nano),
-		Engine:     "opa",
-		Benchmarks: results,
+	if *list {
+		printBenchmarkList(os.Stdout, available)
+		return
 	}
 
-	jsonData, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error marshaling JSON: %v\n", err)
+	if *validate {
+		errs := validatePolicies(runOptions{policyDir: *policyDir, inputPath: *inputPath})
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		if len(errs) > 0 {
+			os.Exit(1)
+		}
+		fmt.Println("All policies compiled")
+		return
+	}
+
+	outFormat, ok := outputFormats[*format]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown -format %q; expected one of %s\n", *format, formatNames())
+		os.Exit(1)
+	}
+	if *format == "sqlite" && !sqliteAvailable {
+		fmt.Fprintln(os.Stderr, "Error: -format=sqlite requires building with -tags opa_sqlite")
+		os.Exit(1)
+	}
+	if *dbPath != "" {
+		if outFormat.writeFile == nil {
+			fmt.Fprintln(os.Stderr, "Error: -db requires -format=sqlite")
+			os.Exit(1)
+		}
+		*output = *dbPath
+	}
+	if *output == "" {
+		*output = "opa-benchmark-results." + outFormat.ext
+	}
+	if *output == "-" && outFormat.writeFile != nil {
+		fmt.Fprintf(os.Stderr, "Error: -format=%s cannot write to stdout\n", *format)
 		os.Exit(1)
 	}
 
-	if err := os.WriteFile(*output, jsonData, 0644); err != nil {
-		fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+	if *warmup < 0 {
+		fmt.Fprintf(os.Stderr, "Error: -warmup must not be negative, got %d\n", *warmup)
+		os.Exit(1)
+	}
+	if *count < 1 {
+		fmt.Fprintf(os.Stderr, "Error: -count must be at least 1, got %d\n", *count)
 		os.Exit(1)
 	}
+	if *samples < minSamples {
+		fmt.Fprintf(os.Stderr, "Error: -samples must be at least %d, got %d\n", minSamples, *samples)
+		os.Exit(1)
+	}
+	if *auto && *maxSamples < *samples {
+		fmt.Fprintf(os.Stderr, "Error: -max-samples must be at least -samples (%d), got %d\n", *samples, *maxSamples)
+		os.Exit(1)
+	}
+	if *auto && *autoThreshold <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: -auto-threshold must be positive, got %g\n", *autoThreshold)
+		os.Exit(1)
+	}
+
+	opts := runOptions{
+		warmup:        *warmup,
+		samples:       *samples,
+		trimOutliers:  *trim,
+		keepSamples:   *raw || *count > 1,
+		policyDir:     *policyDir,
+		wasm:          *wasm,
+		prepare:       *prepare,
+		parallel:      *parallel,
+		timeout:       *timeout,
+		inputPath:     *inputPath,
+		auto:          *auto,
+		autoThreshold: *autoThreshold,
+		maxSamples:    *maxSamples,
+		sizes:         sizes,
+		seed:          *seed,
+		quiet:         *quiet,
+		verbose:       *verbose,
+	}
+	if *only != "" {
+		if *filter != "" {
+			fmt.Fprintln(os.Stderr, "Error: -only and -filter cannot be combined")
+			os.Exit(1)
+		}
+		if !hasBenchmark(available, *only) {
+			fmt.Fprintf(os.Stderr, "Error: unknown benchmark %q for -only; run with -list to see the names\n", *only)
+			os.Exit(1)
+		}
+		opts.filter = regexp.MustCompile("^" + regexp.QuoteMeta(*only) + "$")
+	}
+	if *filter != "" {
+		re, err := regexp.Compile(*filter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid -filter: %v\n", err)
+			os.Exit(1)
+		}
+		opts.filter = re
+	}
+
+	// The latest of the -baseline files is the one compared against.
+	var baselines []baselineRun
+	var baseline baselineRun
+	if len(baselinePaths) > 0 {
+		var err error
+		if baselines, err = loadBaselines(baselinePaths); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		baseline = baselines[len(baselines)-1]
+	}
+
+	// A streaming format writes each result as its benchmark finishes; -output
+	// - writes to stdout. With -count above 1 a benchmark's row is only
+	// known once its runs are aggregated, so the rows are written after
+	// the last run instead.
+	var stream io.Writer
+	var streamErr error
+	var emit func(BenchmarkResult)
+	if outFormat.writeResult != nil {
+		stream = os.Stdout
+		if *output != "-" {
+			f, err := os.Create(*output)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			stream = f
+		}
+		emit = func(r BenchmarkResult) {
+			if streamErr == nil {
+				streamErr = outFormat.writeResult(stream, r)
+			}
+		}
+		if *count == 1 {
+			opts.onResult = emit
+		}
+	}
+
+	// Progress, the summary, and the baseline comparison go to console,
+	// which is stderr when -output - puts the results on stdout; -quiet
+	// leaves only the results file and errors on stderr.
+	var console io.Writer = os.Stdout
+	if *output == "-" {
+		console = os.Stderr
+	}
+	if *quiet {
+		console = io.Discard
+	}
+	opts.console = console
 
-	fmt.Printf("\nResults written to: %s\n", *output)
+	fmt.Fprintln(console, "OPA Benchmark Runner")
+	fmt.Fprintln(console, "====================")
+	fmt.Fprintf(console, "OPA %s, %s %s/%s\n", version.Version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
 
-	fmt.Println("\nBenchmark summary:")
-	for _, b := range results {
-		fmt.Printf("  %-35s %10d ns (std: %d)\n",
+	if *maxprocs > 0 {
+		runtime.GOMAXPROCS(*maxprocs)
+	}
+	if *pin >= 0 {
+		if err := pinToCPU(*pin); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Ctrl-C stops the run; the results collected so far are still
+	// written. A second Ctrl-C exits immediately.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var runs [][]BenchmarkResult
+	// drift is the drift of the run furthest from a steady state, or 0
+	// if none was measured.
+	drift := 0.0
+	runSuite := func() error {
+		for i := 1; i <= *count && ctx.Err() == nil; i++ {
+			if *count > 1 {
+				fmt.Fprintf(console, "\nRun %d of %d\n", i, *count)
+			}
+			run, runDrift, err := runAllBenchmarks(ctx, opts)
+			if err != nil {
+				return err
+			}
+			runs = append(runs, run)
+			if runDrift > 0 && (drift == 0 || math.Abs(runDrift-1) > math.Abs(drift-1)) {
+				drift = runDrift
+			}
+		}
+		return nil
+	}
+	err = withCPUProfile(*cpuProfile, func() error {
+		return withTrace(*tracePath, runSuite)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := writeHeapProfile(*memProfile); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if ctx.Err() != nil {
+		stop()
+		fmt.Fprintln(console, "\nInterrupted; writing partial results")
+	}
+	results := aggregateRuns(runs, opts)
+	if !*raw {
+		// Kept only for aggregateRuns to pool.
+		for i := range results {
+			results[i].Samples = nil
+		}
+	}
+	if emit != nil && *count > 1 {
+		for _, r := range results {
+			if !synthetic(r) {
+				emit(r)
+			}
+		}
+	}
+	if *polix && ctx.Err() == nil {
+		fmt.Fprintln(console, "\nRunning polix benchmarks...")
+		polixResults, err := runPolixBenchmarks(ctx, *polixDir, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, r := range polixResults {
+			if emit != nil {
+				emit(r)
+			}
+		}
+		results = append(results, polixResults...)
+	}
+	speedups, warnings := engineSpeedups(results)
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
+
+	data := ResultsOutput{
+		SchemaVersion:    schemaVersion,
+		Timestamp:        time.Now().UTC().Format(time.RFC3339Nano),
+		Engine:           "opa",
+		Metadata:         collectMetadata(opts.seed),
+		WarmupIterations: opts.warmup,
+		SampleIterations: opts.samples,
+		Count:            *count,
+		Benchmarks:       results,
+		Speedups:         speedups,
+	}
+	data.Metadata.Drift = drift
+	if len(baselines) > 0 {
+		data.comparisons = compareResults(baseline.data.Benchmarks, results, *threshold)
+		data.regressionThreshold = *threshold
+	}
+
+	switch {
+	case stream != nil:
+		if streamErr != nil {
+			fmt.Fprintf(os.Stderr, "Error writing file: %v\n", streamErr)
+			os.Exit(1)
+		}
+	case outFormat.writeFile != nil:
+		if err := outFormat.writeFile(*output, data); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	case *output == "-":
+		if err := outFormat.write(os.Stdout, data); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		var out bytes.Buffer
+		if err := outFormat.write(&out, data); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(*output, out.Bytes(), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *output != "-" {
+		fmt.Fprintf(console, "\nResults written to: %s\n", *output)
+	}
+
+	fmt.Fprintln(console, "\nBenchmark summary:")
+	unstable := 0
+	var suite *BenchmarkResult
+	var summaries []BenchmarkResult
+	for i, b := range results {
+		if b.Name == geomeanName {
+			suite = &results[i]
+			continue
+		}
+		if synthetic(b) {
+			summaries = append(summaries, b)
+			continue
+		}
+		if b.Error != "" {
+			fmt.Fprintf(console, "  %-35s %10s: %s\n", b.Name, "error", b.Error)
+			continue
+		}
+		if !measured(b) {
+			status := "timed out"
+			if b.Results["interrupted"] == true {
+				status = "interrupted"
+			}
+			fmt.Fprintf(console, "  %-35s %10s after %d samples\n", b.Name, status, b.Results["samples"])
+			continue
+		}
+		marker := " "
+		if b.Results["cv"].(float64) > *cvThreshold {
+			marker = "*"
+			unstable++
+		}
+		if b.Engine == "polix" {
+			fmt.Fprintf(console, "%s %-35s %10d ns (std: %d, cv: %.2f)\n",
+				marker, b.Name, b.Results["mean-ns"], b.Results["std-dev"], b.Results["cv"])
+			continue
+		}
+		fmt.Fprintf(console, "%s %-35s %10d ns %10.0f ops/s ±%4.1f%% (95%% CI: %d-%d, median: %d, p90: %d, p95: %d, p99: %d, std: %d, cv: %.2f)\n",
+			marker,
 			b.Name,
 			b.Results["mean-ns"],
-			b.Results["std-dev"])
+			b.Results["ops-per-sec"],
+			b.Results["rel-margin"],
+			b.Results["ci-95-low"],
+			b.Results["ci-95-high"],
+			b.Results["median-ns"],
+			b.Results["p90-ns"],
+			b.Results["p95-ns"],
+			b.Results["p99-ns"],
+			b.Results["std-dev"],
+			b.Results["cv"])
+		if rv, ok := b.Results["run-variance"]; ok {
+			fmt.Fprintf(console, "  %-35s %10s    (across %d runs, std-dev of mean: %d)\n", "", "", b.Results["runs"], rv)
+		}
+	}
+	if len(summaries) > 0 {
+		fmt.Fprintln(console, "\nCategory geomeans:")
+		for _, b := range summaries {
+			fmt.Fprintf(console, "  %-35s %10d ns across %d benchmarks\n",
+				category(b.Name), b.Results["mean-ns"], b.Results["benchmarks"])
+		}
+	}
+	if suite != nil {
+		fmt.Fprintf(console, "\n  %-35s %10d ns across %d benchmarks\n",
+			"Suite geomean:", suite.Results["mean-ns"], suite.Results["benchmarks"])
+	}
+	if len(speedups) > 0 {
+		fmt.Fprintln(console, "\nOPA vs polix (speedup = opa mean-ns / polix mean-ns):")
+		for _, sp := range speedups {
+			fmt.Fprintf(console, "  %-35s %10.0f ns %10.0f ns %8.1fx\n", sp.Name, sp.OPAMeanNs, sp.PolixMeanNs, sp.Speedup)
+		}
+	}
+	if unstable > 0 {
+		fmt.Fprintf(console, "\n* %d unstable benchmark(s) with cv above %.2f; re-run before trusting them\n",
+			unstable, *cvThreshold)
+	}
+
+	if len(baselines) > 1 {
+		fmt.Fprintf(console, "\nTrend of mean-ns across %d baselines and this run:\n", len(baselines))
+		printTrend(console, baselines, results)
+	}
+	if len(baselines) > 0 {
+		fmt.Fprintf(console, "\nComparison with %s:\n", baseline.path)
+		// A bumped OPA dependency shifts the numbers as much as our own
+		// changes do.
+		if v := baseline.data.Metadata.OPAVersion; v != "" && v != version.Version {
+			fmt.Fprintf(console, "  Note: the baseline ran OPA %s and this run OPA %s\n", v, version.Version)
+		}
+		if n := printComparison(console, data.comparisons, *threshold); n > 0 {
+			if *quiet {
+				fmt.Fprintf(os.Stderr, "Error: %d benchmark(s) regressed by more than %.1f%%\n", n, *threshold)
+			}
+			os.Exit(1)
+		}
 	}
 }
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// outputFormat renders a results file for the -format flag. A streaming
+// format writes each result as its benchmark finishes, through
+// writeResult, instead of the whole file at the end; a database format
+// updates the file at a path through writeFile.
+type outputFormat struct {
+	ext         string
+	write       func(w io.Writer, data ResultsOutput) error
+	writeResult func(w io.Writer, r BenchmarkResult) error
+	writeFile   func(path string, data ResultsOutput) error
+}
+
+var outputFormats = map[string]outputFormat{
+	"json":       {"json", writeJSON, nil, nil},
+	"csv":        {"csv", writeCSV, nil, nil},
+	"markdown":   {"md", writeMarkdown, nil, nil},
+	"benchstat":  {"txt", writeBenchstat, nil, nil},
+	"junit":      {"xml", writeJUnit, nil, nil},
+	"prometheus": {"prom", writePrometheus, nil, nil},
+	"ndjson":     {"ndjson", nil, writeNDJSONResult, nil},
+	"sqlite":     {"db", nil, nil, writeSQLite},
+	"html":       {"html", writeHTML, nil, nil},
+}
+
+func formatNames() string {
+	names := make([]string, 0, len(outputFormats))
+	for name := range outputFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func writeJSON(w io.Writer, data ResultsOutput) error {
+	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling JSON: %w", err)
+	}
+	_, err = w.Write(jsonData)
+	return err
+}
+
+// writeNDJSONResult writes r as one line of newline-delimited JSON.
+func writeNDJSONResult(w io.Writer, r BenchmarkResult) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("marshaling JSON: %w", err)
+	}
+	_, err = w.Write(append(line, '\n'))
+	return err
+}
+
+// formatValue renders a Results value as a plain number; missing values,
+// such as the statistics of the synthetic summary results, are empty.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// csvColumns is the fixed column order of the CSV output after the name.
+var csvColumns = []string{"mean-ns", "std-dev", "lower-q", "upper-q", "samples"}
+
+func writeCSV(w io.Writer, data ResultsOutput) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"name"}, csvColumns...)); err != nil {
+		return err
+	}
+	for _, b := range data.Benchmarks {
+		row := []string{b.Name}
+		for _, col := range csvColumns {
+			row = append(row, formatValue(b.Results[col]))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeMarkdown renders the results as a GitHub-flavored table for PR
+// comments, with the numeric columns right-aligned.
+func writeMarkdown(w io.Writer, data ResultsOutput) error {
+	if _, err := fmt.Fprintln(w, "| Benchmark | Mean (ns) | Median (ns) | Std dev (ns) | CV |"); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w, "| :--- | ---: | ---: | ---: | ---: |"); err != nil {
+		return err
+	}
+	for _, b := range data.Benchmarks {
+		cv := ""
+		if v, ok := b.Results["cv"].(float64); ok {
+			cv = strconv.FormatFloat(v, 'f', 2, 64)
+		}
+		if _, err := fmt.Fprintf(w, "| %s | %s | %s | %s | %s |\n",
+			b.Name,
+			formatValue(b.Results["mean-ns"]),
+			formatValue(b.Results["median-ns"]),
+			formatValue(b.Results["std-dev"]),
+			cv); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// category returns the category of a result name, the name without its
+// last segment: opa/quantifier for opa/quantifier/forall-small-satisfied
+// and opa for the plain opa/simple-satisfied.
+func category(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
+
+// benchstatName turns a result name such as opa/simple-satisfied into a Go
+// benchmark name such as BenchmarkOpaSimpleSatisfied.
+func benchstatName(name string) string {
+	var sb strings.Builder
+	sb.WriteString("Benchmark")
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		sb.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return sb.String()
+}
+
+// writeBenchstat renders the results in the output format of go test
+// -bench, so they can be compared with benchstat. mean-ns is reported as
+// ns/op and samples as the iteration count. The synthetic geomean and
+// category summaries are left out; benchstat computes its own.
+func writeBenchstat(w io.Writer, data ResultsOutput) error {
+	fmt.Fprintf(w, "goos: %s\n", data.Metadata.GOOS)
+	fmt.Fprintf(w, "goarch: %s\n", data.Metadata.GOARCH)
+	fmt.Fprintf(w, "pkg: opa-bench\n")
+	if data.Metadata.CPUModel != "" {
+		fmt.Fprintf(w, "cpu: %s\n", data.Metadata.CPUModel)
+	}
+	procs := data.Metadata.GOMAXPROCS
+	for _, b := range data.Benchmarks {
+		if synthetic(b) || !measured(b) {
+			continue
+		}
+		line := fmt.Sprintf("%s-%d\t%s\t%s ns/op",
+			benchstatName(b.Name), procs,
+			formatValue(b.Results["samples"]),
+			formatValue(b.Results["mean-ns"]))
+		if bytes, ok := b.Results["bytes-per-op"]; ok {
+			line += fmt.Sprintf("\t%s B/op", formatValue(bytes))
+		}
+		if allocs, ok := b.Results["allocs-per-op"]; ok {
+			line += fmt.Sprintf("\t%s allocs/op", formatValue(allocs))
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prometheusMetrics are the gauges of the Prometheus output and the
+// Results key each one reports.
+var prometheusMetrics = []struct {
+	name string
+	key  string
+	help string
+}{
+	{"opa_benchmark_mean_ns", "mean-ns", "Mean evaluation time in nanoseconds."},
+	{"opa_benchmark_std_dev_ns", "std-dev", "Standard deviation of the evaluation time in nanoseconds."},
+	{"opa_benchmark_median_ns", "median-ns", "Median evaluation time in nanoseconds."},
+	{"opa_benchmark_p90_ns", "p90-ns", "90th percentile evaluation time in nanoseconds."},
+	{"opa_benchmark_p95_ns", "p95-ns", "95th percentile evaluation time in nanoseconds."},
+	{"opa_benchmark_p99_ns", "p99-ns", "99th percentile evaluation time in nanoseconds."},
+	{"opa_benchmark_cold_ns", "cold-ns", "First evaluation time in nanoseconds."},
+	{"opa_benchmark_ops_per_second", "ops-per-sec", "Evaluations per second on one core, from the mean."},
+}
+
+// prometheusLabel escapes s for use as a label value in the Prometheus
+// text exposition format.
+var prometheusLabel = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writePrometheus renders the results in the Prometheus text exposition
+// format, for the node_exporter textfile collector. Each statistic is a
+// gauge labelled by benchmark name; results without a statistic, such as
+// the percentiles of the synthetic summaries, are left out of that gauge.
+func writePrometheus(w io.Writer, data ResultsOutput) error {
+	for _, m := range prometheusMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(w, "# TYPE %s gauge\n", m.name)
+		for _, b := range data.Benchmarks {
+			v, ok := resultValue(b, m.key)
+			if !ok {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "%s{name=\"%s\"} %s\n",
+				m.name, prometheusLabel.Replace(b.Name), strconv.FormatFloat(v, 'f', -1, 64)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
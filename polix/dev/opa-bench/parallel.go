@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+// benchJob is a benchmark with the prepared query it times.
+type benchJob struct {
+	b     benchDef
+	query rego.PreparedEvalQuery
+}
+
+// runParallel runs jobs on opts.parallel worker goroutines and returns
+// their results sorted by name. Each worker is locked to its own OS
+// thread and pinned to a CPU core, round robin, so concurrent benchmarks
+// do not migrate onto each other's cores. Memory statistics are process
+// wide, so gc-count and the allocation figures of concurrent benchmarks
+// include each other's garbage.
+func runParallel(ctx context.Context, jobs []benchJob, opts runOptions) []BenchmarkResult {
+	jobCh := make(chan benchJob)
+	resultCh := make(chan BenchmarkResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.parallel; i++ {
+		wg.Add(1)
+		go func(cpu int) {
+			defer wg.Done()
+			if err := pinToCPU(cpu); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: worker not pinned: %v\n", err)
+			}
+			for j := range jobCh {
+				resultCh <- runBenchmark(ctx, j.b.name, j.query, j.b.doc, opts)
+			}
+		}(i % runtime.NumCPU())
+	}
+
+	go func() {
+		defer close(jobCh)
+		for _, j := range jobs {
+			select {
+			case jobCh <- j:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(resultCh)
+	}()
+
+	// Progress and opts.onResult are only touched from this goroutine.
+	results := make([]BenchmarkResult, 0, len(jobs))
+	for r := range resultCh {
+		opts.started(r.Name)
+		opts.finished(r)
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+// preparePartialQuery partially evaluates query against the embedded
+// policy filename with the whole input unknown, and prepares the residual
+// for evaluation.
+//
+// Partial evaluation inlines rule bodies and constant data, leaving only
+// the conditions on input. The residual is a disjunction of bodies. A
+// single body is evaluated as the query itself; several become a module
+// with one allow rule per body. No bodies means the query is undefined
+// for every input, which becomes a module whose allow is always false.
+// Support modules that partial evaluation could not inline, such as rules
+// with a default, are loaded alongside.
+func preparePartialQuery(name string, filename string, query string) (PreparedPolicy, error) {
+	ctx := context.Background()
+
+	policyBytes, err := policies.ReadFile("policies/" + filename)
+	if err != nil {
+		return PreparedPolicy{}, fmt.Errorf("reading %s: %w", filename, err)
+	}
+
+	pq, err := rego.New(
+		rego.Query(query),
+		rego.Module(filename, string(policyBytes)),
+		rego.Unknowns([]string{"input"}),
+	).PrepareForPartial(ctx)
+	if err != nil {
+		return PreparedPolicy{}, fmt.Errorf("preparing partial %s: %w", name, err)
+	}
+
+	residual, err := pq.Partial(ctx)
+	if err != nil {
+		return PreparedPolicy{}, fmt.Errorf("partially evaluating %s: %w", name, err)
+	}
+
+	var options []func(*rego.Rego)
+	switch len(residual.Queries) {
+	case 0:
+		options = append(options,
+			rego.Query("data.partial.allow"),
+			rego.Module("partial.rego", "package partial\n\ndefault allow := false\n"))
+	case 1:
+		options = append(options, rego.ParsedQuery(residual.Queries[0]))
+	default:
+		var module strings.Builder
+		module.WriteString("package partial\n")
+		for _, body := range residual.Queries {
+			fmt.Fprintf(&module, "\nallow if {\n\t%s\n}\n", body)
+		}
+		options = append(options,
+			rego.Query("data.partial.allow"),
+			rego.Module("partial.rego", module.String()))
+	}
+	for _, support := range residual.Support {
+		options = append(options, rego.ParsedModule(support))
+	}
+
+	prepared, err := rego.New(options...).PrepareForEval(ctx)
+	if err != nil {
+		return PreparedPolicy{}, fmt.Errorf("preparing residual of %s: %w", name, err)
+	}
+
+	return PreparedPolicy{Name: name, Query: prepared}, nil
+}
+
+// partialBenchmarks mirrors plainBenchmarks under opa/partial/, evaluating
+// the partially evaluated residual of each policy against the same
+// documents.
+var partialBenchmarks = func() []benchDef {
+	defs := make([]benchDef, len(plainBenchmarks))
+	for i, b := range plainBenchmarks {
+		b.name = "opa/partial/" + strings.TrimPrefix(b.name, "opa/")
+		defs[i] = b
+	}
+	return defs
+}()
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package main
+
+import "runtime"
+
+// pinToCPU locks the calling goroutine to its OS thread. CPU affinity is
+// only set on Linux; elsewhere cpu is ignored.
+func pinToCPU(cpu int) error {
+	runtime.LockOSThread()
+	return nil
+}
@@ -1,5 +1,7 @@
 package policy.complex
 
+default allow := false
+
 # Superadmin path
 allow if {
 	input.role == "superadmin"
@@ -1,5 +1,16 @@
 package policy.count_filter
 
+default count_simple := false
+default count_medium := false
+default count_large := false
+default count_nested := false
+default count_with_comparison := false
+default forall_filtered := false
+default exists_filtered := false
+default count_filtered := false
+default count_filtered_complex := false
+default nested_filtered := false
+
 # Count simple - count users >= 5
 count_simple if {
 	count(input.users) >= 5
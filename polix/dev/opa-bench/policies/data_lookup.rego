@@ -0,0 +1,16 @@
+package policy.data_lookup
+
+default user_active := false
+default role_granted := false
+
+# User active - keyed lookup of input.user in the data directory
+user_active if {
+	data.directory.users[input.user].active
+}
+
+# Role granted - scan the data grants for one matching the input
+role_granted if {
+	some grant in data.directory.grants
+	grant.user == input.user
+	grant.role == input.role
+}
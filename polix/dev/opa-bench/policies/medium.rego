@@ -1,5 +1,7 @@
 package policy.medium
 
+default allow := false
+
 allow if {
 	input.role == "admin"
 	input.level > 5
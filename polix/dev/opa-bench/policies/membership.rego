@@ -0,0 +1,16 @@
+package policy.membership
+
+default set_member := false
+default array_member := false
+
+allowed_set := {u | some u in data.allowed_users}
+
+# Set member - input.user in the allowlist collected into a set
+set_member if {
+	input.user in allowed_set
+}
+
+# Array member - input.user in the allowlist array itself
+array_member if {
+	input.user in data.allowed_users
+}
@@ -0,0 +1,32 @@
+package policy.nested
+
+default depth_1 := false
+default depth_5 := false
+default depth_10 := false
+default depth_25 := false
+default depth_50 := false
+
+# Depth 1 - value nested under 1 key
+depth_1 if {
+	input.a.value == true
+}
+
+# Depth 5 - value nested under 5 keys
+depth_5 if {
+	input.a.b.c.d.e.value == true
+}
+
+# Depth 10 - value nested under 10 keys
+depth_10 if {
+	input.a.b.c.d.e.f.g.h.i.j.value == true
+}
+
+# Depth 25 - value nested under 25 keys
+depth_25 if {
+	input.a.b.c.d.e.f.g.h.i.j.k.l.m.n.o.p.q.r.s.t.u.v.w.x.y.value == true
+}
+
+# Depth 50 - value nested under 50 keys
+depth_50 if {
+	input.a.b.c.d.e.f.g.h.i.j.k.l.m.n.o.p.q.r.s.t.u.v.w.x.y.z.a.b.c.d.e.f.g.h.i.j.k.l.m.n.o.p.q.r.s.t.u.v.w.x.value == true
+}
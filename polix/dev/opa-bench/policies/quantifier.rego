@@ -1,5 +1,10 @@
 package policy.quantifier
 
+default forall_simple := false
+default forall_nested := false
+default exists_simple := false
+default nested_forall_exists := false
+
 # Forall - all users must be active
 forall_simple if {
 	every u in input.users {
@@ -0,0 +1,8 @@
+package policy.regex
+
+default path_allowed := false
+
+# Path allowed - input.path is the path of a resource under the API
+path_allowed if {
+	regex.match(`^/api/v[0-9]+/(users|teams|orgs)(/[a-z0-9-]+)+$`, input.path)
+}
@@ -1,5 +1,7 @@
 package policy.simple
 
+default allow := false
+
 allow if {
 	input.role == "admin"
 }
@@ -0,0 +1,26 @@
+package policy.wide
+
+default keys_10 := false
+default keys_100 := false
+default keys_1000 := false
+default keys_5000 := false
+
+# Keys 10 - read the last of 10 top-level keys
+keys_10 if {
+	input.field9 == 9
+}
+
+# Keys 100 - read the last of 100 top-level keys
+keys_100 if {
+	input.field99 == 99
+}
+
+# Keys 1000 - read the last of 1000 top-level keys
+keys_1000 if {
+	input.field999 == 999
+}
+
+# Keys 5000 - read the last of 5000 top-level keys
+keys_5000 if {
+	input.field4999 == 4999
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+// readInput parses the JSON input document at path. ok is false when the
+// file does not exist.
+func readInput(path string) (doc map[string]interface{}, ok bool, err error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, false, fmt.Errorf("parsing input %s: %w", path, err)
+	}
+	return doc, true, nil
+}
+
+// readQuery returns the trimmed rego query in path, or "" when the file
+// does not exist.
+func readQuery(path string) (string, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	query := strings.TrimSpace(string(raw))
+	if query == "" {
+		return "", fmt.Errorf("query file %s is empty", path)
+	}
+	return query, nil
+}
+
+// dirBenchmarks returns one benchmark per .rego file in dir, named after
+// the file: policies/authz.rego becomes opa/authz, queried as
+// data.policy.authz.allow. The input document is read from authz.json
+// next to the policy when present, and is empty otherwise. Likewise an
+// authz.query file overrides the query, for policies whose entrypoint is
+// not data.policy.<name>.allow.
+//
+// Their decisions are unknown, so unlike the embedded suite they are not
+// checked against expected.
+//
+// A non-empty inputPath replaces those documents: every policy is run
+// against it, in a benchmark named after both files, such as
+// opa/authz/captured-request for captured-request.json.
+func dirBenchmarks(dir string, inputPath string) ([]benchDef, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.rego"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no .rego files in %s", dir)
+	}
+	sort.Strings(paths)
+
+	var input map[string]interface{}
+	if inputPath != "" {
+		var ok bool
+		if input, ok, err = readInput(inputPath); err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, fmt.Errorf("input file %s does not exist", inputPath)
+		}
+	}
+
+	var defs []benchDef
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".rego")
+		query, err := readQuery(strings.TrimSuffix(path, ".rego") + ".query")
+		if err != nil {
+			return nil, err
+		}
+		if input != nil {
+			inputName := strings.TrimSuffix(filepath.Base(inputPath), ".json")
+			defs = append(defs, benchDef{"opa/" + name + "/" + inputName, name, input, false, query, nil})
+			continue
+		}
+		doc, ok, err := readInput(strings.TrimSuffix(path, ".rego") + ".json")
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			doc = map[string]interface{}{}
+		}
+		defs = append(defs, benchDef{"opa/" + name, name, doc, false, query, nil})
+	}
+	return defs, nil
+}
+
+func prepareDirPolicy(dir string, b benchDef) (PreparedPolicy, error) {
+	name := b.policy
+	filename := name + ".rego"
+	policyBytes, err := os.ReadFile(filepath.Join(dir, filename))
+	if err != nil {
+		return PreparedPolicy{}, fmt.Errorf("reading %s: %w", filename, err)
+	}
+
+	query := b.query
+	if query == "" {
+		query = "data.policy." + name + ".allow"
+	}
+
+	prepared, err := rego.New(
+		rego.Query(query),
+		rego.Module(filename, string(policyBytes)),
+	).PrepareForEval(context.Background())
+	if err != nil {
+		return PreparedPolicy{}, fmt.Errorf("preparing %s in %s: %w", name, filename, err)
+	}
+
+	return PreparedPolicy{Name: name, Query: prepared}, nil
+}
+
+// runDirBenchmarks runs the benchmarks of opts.policyDir in place of the
+// embedded suite.
+func runDirBenchmarks(ctx context.Context, opts runOptions) ([]BenchmarkResult, error) {
+	defs, err := dirBenchmarks(opts.policyDir, opts.inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := opts.selected(defs)
+	if len(selected) == 0 {
+		return nil, noMatchError(opts, []benchGroup{{opts.policyDir, defs}})
+	}
+
+	opts.progress = newProgress(opts.console, len(selected), opts.quiet, opts.verbose)
+	defer opts.progress.end()
+	opts.progress.section("benchmarks from " + opts.policyDir)
+	var results []BenchmarkResult
+	for _, b := range selected {
+		if ctx.Err() != nil {
+			break
+		}
+		p, err := prepareDirPolicy(opts.policyDir, b)
+		if err != nil {
+			return nil, err
+		}
+		opts.started(b.name)
+		result := runBenchmark(ctx, b.name, p.Query, b.doc, opts)
+		results = append(results, result)
+		opts.finished(result)
+	}
+
+	return withSummaries(results), nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+func TestDirBenchmarksQuery(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"authz.rego":  "package authz.main\n\ndecision := input.role == \"admin\"\n",
+		"authz.query": "data.authz.main.decision\n",
+		"authz.json":  `{"role": "admin"}`,
+		"basic.rego":  "package policy.basic\n\nallow := true\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defs, err := dirBenchmarks(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defs) != 2 {
+		t.Fatalf("dirBenchmarks returned %d benchmarks, want 2", len(defs))
+	}
+	queries := map[string]string{"opa/authz": "data.authz.main.decision", "opa/basic": ""}
+	for _, b := range defs {
+		if b.query != queries[b.name] {
+			t.Errorf("%s query = %q, want %q", b.name, b.query, queries[b.name])
+		}
+		p, err := prepareDirPolicy(dir, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs, err := p.Query.Eval(context.Background(), rego.EvalInput(b.doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rs) != 1 || rs[0].Expressions[0].Value != true {
+			t.Errorf("%s evaluated to %v, want true", b.name, rs)
+		}
+	}
+}
+
+func TestDirBenchmarksEmptyQuery(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"authz.rego":  "package policy.authz\n\nallow := true\n",
+		"authz.query": "  \n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := dirBenchmarks(dir, ""); err == nil {
+		t.Error("dirBenchmarks accepted an empty query file")
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// polixCategories maps the name prefixes of the polix CI runner
+// (polix.bench.runner/run-ci) to the polix/* names used here. Each mapped
+// category evaluates the policies of policies/*.rego over the documents of
+// documents.go, so polix/simple-satisfied and opa/simple-satisfied measure
+// the same decision. The other polix categories have no OPA counterpart
+// and are dropped.
+var polixCategories = []struct {
+	prefix string
+	name   string
+}{
+	{"eval-compiled/", "polix/"},
+	{"quantifier/", "polix/quantifier/"},
+	{"count/", "polix/count/"},
+	{"filtered/", "polix/filtered/"},
+}
+
+// polixIntKeys are the result keys the polix runner writes as integers.
+var polixIntKeys = []string{"mean-ns", "std-dev", "lower-q", "upper-q", "ci-95-low", "ci-95-high", "samples", "gc-count"}
+
+// runPolixBenchmarks runs the polix CI runner with the Clojure CLI in
+// dir, the polix project root, and returns its evaluation benchmarks
+// under polix/* names, selected by opts.filter. opts.samples and
+// opts.warmup are passed on as the runner's :samples and :warmup.
+func runPolixBenchmarks(ctx context.Context, dir string, opts runOptions) ([]BenchmarkResult, error) {
+	tmp, err := os.MkdirTemp("", "opa-bench-polix")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+	output := filepath.Join(tmp, "polix-results.json")
+
+	cmd := exec.CommandContext(ctx, "clojure", "-X:bench-ci",
+		":output", strconv.Quote(output),
+		":samples", strconv.Itoa(opts.samples),
+		":warmup", strconv.Itoa(opts.warmup))
+	cmd.Dir = dir
+	cmd.Stdout = opts.console
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running polix benchmarks in %s: %w", dir, err)
+	}
+
+	raw, err := os.ReadFile(output)
+	if err != nil {
+		return nil, fmt.Errorf("reading polix results: %w", err)
+	}
+	var data ResultsOutput
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("parsing polix results: %w", err)
+	}
+
+	var results []BenchmarkResult
+	for _, r := range polixResults(data.Benchmarks) {
+		if opts.filter == nil || opts.filter.MatchString(r.Name) {
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+// polixResults renames the mapped polix benchmarks and tags them with
+// their engine, and derives cv, ops-per-sec and rel-margin from
+// criterium's statistics. Each criterium sample times a batch of calls, so
+// median-ns, p90-ns, p95-ns, p99-ns and std-err have no polix counterpart
+// and are absent; lower-q and upper-q are criterium's 2.5% and 97.5% tail
+// quantiles rather than quartiles.
+func polixResults(benchmarks []BenchmarkResult) []BenchmarkResult {
+	var results []BenchmarkResult
+	for _, b := range benchmarks {
+		for _, c := range polixCategories {
+			if !strings.HasPrefix(b.Name, c.prefix) {
+				continue
+			}
+			r := BenchmarkResult{
+				Name:    c.name + strings.TrimPrefix(b.Name, c.prefix),
+				Engine:  "polix",
+				Results: make(map[string]interface{}, len(b.Results)+1),
+			}
+			for k, v := range b.Results {
+				r.Results[k] = v
+			}
+			for _, k := range polixIntKeys {
+				if v, ok := resultValue(b, k); ok {
+					r.Results[k] = int64(v)
+				}
+			}
+			m, _ := resultValue(b, "mean-ns")
+			sd, _ := resultValue(b, "std-dev")
+			r.Results["cv"] = 0.0
+			if m > 0 {
+				r.Results["cv"] = sd / m
+				r.Results["ops-per-sec"] = 1e9 / m
+				lo, okLo := resultValue(b, "ci-95-low")
+				hi, okHi := resultValue(b, "ci-95-high")
+				if okLo && okHi {
+					r.Results["rel-margin"] = (hi - lo) / 2 / m * 100
+				}
+			}
+			results = append(results, r)
+			break
+		}
+	}
+	return results
+}
+
+// EngineSpeedup compares one benchmark across the two engines. Speedup
+// above 1 means polix is faster.
+type EngineSpeedup struct {
+	Name        string  `json:"name"`
+	OPAMeanNs   float64 `json:"opa-mean-ns"`
+	PolixMeanNs float64 `json:"polix-mean-ns"`
+	Speedup     float64 `json:"speedup"`
+}
+
+// engineSpeedups matches every polix/* result to the opa/* result of the
+// same logical name and returns opa mean-ns / polix mean-ns for each. A
+// polix result without a measured counterpart is skipped with a warning.
+func engineSpeedups(results []BenchmarkResult) ([]EngineSpeedup, []string) {
+	opa := make(map[string]BenchmarkResult, len(results))
+	for _, r := range results {
+		if r.Engine == "" && strings.HasPrefix(r.Name, "opa/") {
+			opa[strings.TrimPrefix(r.Name, "opa/")] = r
+		}
+	}
+
+	var speedups []EngineSpeedup
+	var warnings []string
+	for _, r := range results {
+		if r.Engine != "polix" {
+			continue
+		}
+		name := strings.TrimPrefix(r.Name, "polix/")
+		o, ok := opa[name]
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("no opa/%s to compare %s with", name, r.Name))
+			continue
+		}
+		opaMean, _ := resultValue(o, "mean-ns")
+		polixMean, _ := resultValue(r, "mean-ns")
+		if opaMean <= 0 || polixMean <= 0 {
+			warnings = append(warnings, fmt.Sprintf("skipping %s: no mean-ns on both engines", name))
+			continue
+		}
+		speedups = append(speedups, EngineSpeedup{
+			Name:        name,
+			OPAMeanNs:   opaMean,
+			PolixMeanNs: polixMean,
+			Speedup:     opaMean / polixMean,
+		})
+	}
+	return speedups, warnings
+}
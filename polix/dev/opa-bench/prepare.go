@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"time"
+)
+
+// prepareBenchmarks time preparing a query against each embedded policy,
+// the parsing and compilation OPA repeats whenever a policy update
+// activates a new bundle. opa/prepare/data-lookup also loads the 10k-user
+// dataDirectory into a fresh store. doc and expected are unused.
+var prepareBenchmarks = []benchDef{
+	{"opa/prepare/simple", "simple", nil, false, "data.policy.simple.allow", nil},
+	{"opa/prepare/medium", "medium", nil, false, "data.policy.medium.allow", nil},
+	{"opa/prepare/complex", "complex", nil, false, "data.policy.complex.allow", nil},
+	{"opa/prepare/quantifier", "quantifier", nil, false, "data.policy.quantifier.forall_simple", nil},
+	{"opa/prepare/count-filter", "count_filter", nil, false, "data.policy.count_filter.count_simple", nil},
+	{"opa/prepare/nested", "nested", nil, false, "data.policy.nested.depth_50", nil},
+	{"opa/prepare/wide", "wide", nil, false, "data.policy.wide.keys_5000", nil},
+	{"opa/prepare/data-lookup", "data_lookup", nil, false, "data.policy.data_lookup.user_active", dataDirectory},
+}
+
+// runPrepareBenchmarks runs the selected prepareBenchmarks.
+func runPrepareBenchmarks(ctx context.Context, opts runOptions) []BenchmarkResult {
+	selected := opts.selected(prepareBenchmarks)
+	if len(selected) == 0 {
+		return nil
+	}
+
+	opts.progress.section("prepare benchmarks")
+	var results []BenchmarkResult
+	for _, b := range selected {
+		if ctx.Err() != nil {
+			break
+		}
+		opts.started(b.name)
+		result := runPrepareBenchmark(ctx, b, opts)
+		results = append(results, result)
+		opts.finished(result)
+	}
+	return results
+}
+
+// runPrepareBenchmark measures preparing b.query against the embedded
+// policy b.policy from scratch, with a fresh rego.New for every sample, so
+// each sample parses and compiles the module and pays for the garbage
+// that leaves behind.
+func runPrepareBenchmark(ctx context.Context, b benchDef, opts runOptions) BenchmarkResult {
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	prepare := func() error {
+		_, err := prepareDataQuery(b.name, b.policy+".rego", b.query, b.data)
+		return err
+	}
+	if err := prepare(); err != nil {
+		return BenchmarkResult{Name: b.name, Results: map[string]interface{}{}, Error: err.Error()}
+	}
+
+	for i := 0; i < opts.warmup; i++ {
+		prepare()
+		if err := ctx.Err(); err != nil {
+			return stoppedResult(b.name, err, 0)
+		}
+	}
+
+	runtime.GC()
+
+	samples := make([]float64, 0, opts.samples)
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 0; i < opts.samples; i++ {
+		start := time.Now()
+		prepare()
+		samples = append(samples, float64(time.Since(start).Nanoseconds()))
+		if err := ctx.Err(); err != nil {
+			return stoppedResult(b.name, err, len(samples))
+		}
+	}
+	runtime.ReadMemStats(&after)
+
+	return sampleResult(b.name, samples, &before, &after, opts)
+}
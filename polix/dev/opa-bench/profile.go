@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// withCPUProfile runs fn while writing a CPU profile to path. The profile
+// is flushed and closed even when fn fails. An empty path just runs fn.
+func withCPUProfile(path string, fn func() error) (err error) {
+	if path == "" {
+		return fn()
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating CPU profile: %w", err)
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("writing CPU profile: %w", cerr)
+		}
+	}()
+	if err := pprof.StartCPUProfile(f); err != nil {
+		return fmt.Errorf("starting CPU profile: %w", err)
+	}
+	defer pprof.StopCPUProfile()
+	return fn()
+}
+
+// withTrace runs fn while writing an execution trace to path, for viewing
+// with go tool trace. The trace is flushed and closed even when fn fails.
+// An empty path just runs fn.
+func withTrace(path string, fn func() error) (err error) {
+	if path == "" {
+		return fn()
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating trace: %w", err)
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("writing trace: %w", cerr)
+		}
+	}()
+	if err := trace.Start(f); err != nil {
+		return fmt.Errorf("starting trace: %w", err)
+	}
+	defer trace.Stop()
+	return fn()
+}
+
+// writeHeapProfile writes a heap profile to path after a GC, so it shows
+// live memory rather than garbage awaiting collection. An empty path
+// writes nothing.
+func writeHeapProfile(path string) error {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating memory profile: %w", err)
+	}
+	defer f.Close()
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(f); err != nil {
+		return fmt.Errorf("writing memory profile: %w", err)
+	}
+	return f.Close()
+}
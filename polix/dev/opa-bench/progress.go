@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// progress reports benchmarks as they run to w, numbered against the
+// total of the run. On a terminal it rewrites a single line in place;
+// otherwise it logs a line per benchmark, under a heading per group.
+// Quiet progress prints nothing.
+type progress struct {
+	w       io.Writer
+	total   int
+	done    int
+	quiet   bool
+	verbose bool
+	tty     bool
+	// open is set while a terminal progress line awaits its newline.
+	open bool
+}
+
+func newProgress(w io.Writer, total int, quiet, verbose bool) *progress {
+	return &progress{w: w, total: total, quiet: quiet, verbose: verbose, tty: !quiet && isTerminal(w)}
+}
+
+// isTerminal reports whether w is a character device such as a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// section announces a group of benchmarks. On a terminal the heading is
+// left out so it does not break up the progress line.
+func (p *progress) section(label string) {
+	if p.quiet || p.tty {
+		return
+	}
+	fmt.Fprintf(p.w, "Running %s...\n", label)
+}
+
+// start begins the progress line of the named benchmark.
+func (p *progress) start(name string) {
+	p.done++
+	if p.quiet {
+		return
+	}
+	line := fmt.Sprintf("[%d/%d] %s...", p.done, p.total, name)
+	if p.tty {
+		fmt.Fprintf(p.w, "\r\033[K%s", line)
+		p.open = true
+		return
+	}
+	fmt.Fprintf(p.w, "  %s", line)
+}
+
+// finish ends the progress line with the outcome of r. On a terminal a
+// measured result is overwritten by the next benchmark, while errors and
+// stopped benchmarks keep their line.
+func (p *progress) finish(r BenchmarkResult) {
+	if p.quiet {
+		return
+	}
+	if p.tty && r.Error == "" && measured(r) && !p.verbose {
+		fmt.Fprint(p.w, outcome(r))
+		return
+	}
+	fmt.Fprintln(p.w, outcome(r))
+	p.open = false
+	if p.verbose && len(r.sorted) > 0 {
+		writeHistogram(p.w, r.sorted, histogramBuckets)
+	}
+}
+
+// histogramBuckets is the number of buckets of the -verbose histogram.
+const histogramBuckets = 10
+
+// histogram counts sorted into n equal-width buckets spanning its min to
+// its max. When every sample is equal they all fall in the first bucket.
+func histogram(sorted []float64, n int) []int {
+	counts := make([]int, n)
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	width := (hi - lo) / float64(n)
+	for _, v := range sorted {
+		i := 0
+		if width > 0 {
+			i = min(int((v-lo)/width), n-1)
+		}
+		counts[i]++
+	}
+	return counts
+}
+
+// writeHistogram draws the histogram of sorted as one bar per bucket,
+// labelled with the bucket's range in ns and its count.
+func writeHistogram(w io.Writer, sorted []float64, n int) {
+	const barWidth = 40
+	counts := histogram(sorted, n)
+	peak := slices.Max(counts)
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	width := (hi - lo) / float64(n)
+	for i, c := range counts {
+		bar := strings.Repeat("#", c*barWidth/peak)
+		fmt.Fprintf(w, "      %10.0f - %10.0f ns %-*s %d\n",
+			lo+float64(i)*width, lo+float64(i+1)*width, barWidth, bar, c)
+	}
+}
+
+// note prints msg on a line of its own.
+func (p *progress) note(msg string) {
+	if p.quiet {
+		return
+	}
+	p.end()
+	fmt.Fprintln(p.w, msg)
+}
+
+// end finishes a terminal progress line that is still open.
+func (p *progress) end() {
+	if p.open {
+		fmt.Fprintln(p.w)
+		p.open = false
+	}
+}
+
+// outcome describes how a benchmark ended, for its progress line.
+func outcome(r BenchmarkResult) string {
+	switch {
+	case r.Error != "":
+		return " error: " + r.Error
+	case r.Results["timed-out"] == true:
+		return " timed out"
+	case r.Results["interrupted"] == true:
+		return " interrupted"
+	default:
+		return fmt.Sprintf(" %d ns", r.Results["mean-ns"])
+	}
+}
@@ -3,13 +3,22 @@ package main
 import (
 	"context"
 	"embed"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"math"
+	"math/rand"
+	"os"
+	"regexp"
 	"runtime"
+	"slices"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/open-policy-agent/opa/v1/rego"
+	"github.com/open-policy-agent/opa/v1/storage/inmem"
 )
 
 //go:embed policies/*.rego
@@ -18,6 +27,20 @@ var policies embed.FS
 type BenchmarkResult struct {
 	Name    string                 `json:"name"`
 	Results map[string]interface{} `json:"results"`
+	// Samples holds the raw sample durations in ns, in measurement order,
+	// when runOptions.keepSamples is set (the -raw flag), so summary
+	// statistics can be recomputed offline.
+	Samples []float64 `json:"samples,omitempty"`
+	// Error is set, in place of any timing, when the policy failed to
+	// evaluate or produced no decision.
+	Error string `json:"error,omitempty"`
+	// Engine names the engine that ran the benchmark when it is not
+	// ResultsOutput.Engine, as for the polix results of -polix.
+	Engine string `json:"engine,omitempty"`
+
+	// sorted holds the samples the statistics were computed from, in
+	// ascending order, for the -verbose histogram.
+	sorted []float64
 }
 
 type PreparedPolicy struct {
@@ -25,7 +48,15 @@ type PreparedPolicy struct {
 	Query rego.PreparedEvalQuery
 }
 
-func preparePolicy(name string, filename string) (PreparedPolicy, error) {
+// prepareQuery prepares query against the embedded policy filename.
+func prepareQuery(name string, filename string, query string) (PreparedPolicy, error) {
+	return prepareDataQuery(name, filename, query, nil)
+}
+
+// prepareDataQuery prepares query against the embedded policy filename
+// with data loaded into an in-memory store, as a bundle would be. A nil
+// data prepares query without a store.
+func prepareDataQuery(name string, filename string, query string, data map[string]interface{}) (PreparedPolicy, error) {
 	ctx := context.Background()
 
 	policyBytes, err := policies.ReadFile("policies/" + filename)
@@ -33,15 +64,23 @@ func preparePolicy(name string, filename string) (PreparedPolicy, error) {
 		return PreparedPolicy{}, fmt.Errorf("reading %s: %w", filename, err)
 	}
 
-	query, err := rego.New(
-		rego.Query("data.policy."+name+".allow"),
+	options := []func(*rego.Rego){
+		rego.Query(query),
 		rego.Module(filename, string(policyBytes)),
-	).PrepareForEval(ctx)
+	}
+	if data != nil {
+		options = append(options, rego.Store(inmem.NewFromObject(data)))
+	}
+	prepared, err := rego.New(options...).PrepareForEval(ctx)
 	if err != nil {
-		return PreparedPolicy{}, fmt.Errorf("preparing %s: %w", name, err)
+		return PreparedPolicy{}, fmt.Errorf("preparing %s in %s: %w", name, filename, err)
 	}
 
-	return PreparedPolicy{Name: name, Query: query}, nil
+	return PreparedPolicy{Name: name, Query: prepared}, nil
+}
+
+func preparePolicy(name string, filename string) (PreparedPolicy, error) {
+	return prepareQuery(name, filename, "data.policy."+name+".allow")
 }
 
 func preparePolicies() ([]PreparedPolicy, error) {
@@ -66,22 +105,7 @@ func preparePolicies() ([]PreparedPolicy, error) {
 }
 
 func prepareQuantifierPolicy(name string, ruleName string) (PreparedPolicy, error) {
-	ctx := context.Background()
-
-	policyBytes, err := policies.ReadFile("policies/quantifier.rego")
-	if err != nil {
-		return PreparedPolicy{}, fmt.Errorf("reading quantifier.rego: %w", err)
-	}
-
-	query, err := rego.New(
-		rego.Query("data.policy.quantifier."+ruleName),
-		rego.Module("quantifier.rego", string(policyBytes)),
-	).PrepareForEval(ctx)
-	if err != nil {
-		return PreparedPolicy{}, fmt.Errorf("preparing %s: %w", name, err)
-	}
-
-	return PreparedPolicy{Name: name, Query: query}, nil
+	return prepareQuery(name, "quantifier.rego", "data.policy.quantifier."+ruleName)
 }
 
 func prepareQuantifierPolicies() ([]PreparedPolicy, error) {
@@ -107,22 +131,7 @@ func prepareQuantifierPolicies() ([]PreparedPolicy, error) {
 }
 
 func prepareCountFilterPolicy(name string, ruleName string) (PreparedPolicy, error) {
-	ctx := context.Background()
-
-	policyBytes, err := policies.ReadFile("policies/count_filter.rego")
-	if err != nil {
-		return PreparedPolicy{}, fmt.Errorf("reading count_filter.rego: %w", err)
-	}
-
-	query, err := rego.New(
-		rego.Query("data.policy.count_filter."+ruleName),
-		rego.Module("count_filter.rego", string(policyBytes)),
-	).PrepareForEval(ctx)
-	if err != nil {
-		return PreparedPolicy{}, fmt.Errorf("preparing %s: %w", name, err)
-	}
-
-	return PreparedPolicy{Name: name, Query: query}, nil
+	return prepareQuery(name, "count_filter.rego", "data.policy.count_filter."+ruleName)
 }
 
 func prepareCountFilterPolicies() ([]PreparedPolicy, error) {
@@ -153,6 +162,46 @@ func prepareCountFilterPolicies() ([]PreparedPolicy, error) {
 	return prepared, nil
 }
 
+// nestedDepths are the depths of the rules in nested.rego.
+var nestedDepths = []int{1, 5, 10, 25, 50}
+
+func prepareNestedPolicies() ([]PreparedPolicy, error) {
+	var prepared []PreparedPolicy
+	for _, depth := range nestedDepths {
+		rule := fmt.Sprintf("depth_%d", depth)
+		p, err := prepareQuery(rule, "nested.rego", "data.policy.nested."+rule)
+		if err != nil {
+			return nil, err
+		}
+		prepared = append(prepared, p)
+	}
+	return prepared, nil
+}
+
+// wideKeys are the widths of the rules in wide.rego.
+var wideKeys = []int{10, 100, 1000, 5000}
+
+func prepareRegexPolicies() ([]PreparedPolicy, error) {
+	p, err := prepareQuery("path_allowed", "regex.rego", "data.policy.regex.path_allowed")
+	if err != nil {
+		return nil, err
+	}
+	return []PreparedPolicy{p}, nil
+}
+
+func prepareWidePolicies() ([]PreparedPolicy, error) {
+	var prepared []PreparedPolicy
+	for _, keys := range wideKeys {
+		rule := fmt.Sprintf("keys_%d", keys)
+		p, err := prepareQuery(rule, "wide.rego", "data.policy.wide."+rule)
+		if err != nil {
+			return nil, err
+		}
+		prepared = append(prepared, p)
+	}
+	return prepared, nil
+}
+
 func mean(samples []float64) float64 {
 	sum := 0.0
 	for _, s := range samples {
@@ -170,180 +219,1220 @@ func stdDev(samples []float64, mean float64) float64 {
 	return math.Sqrt(sumSq / float64(len(samples)))
 }
 
-func percentile(samples []float64, p float64) float64 {
+// sampleStdDev is the standard deviation of samples with Bessel's
+// correction, the unbiased estimate from a sample of a larger population.
+func sampleStdDev(samples []float64, mean float64) float64 {
+	n := len(samples)
+	if n < 2 {
+		return 0
+	}
+	return stdDev(samples, mean) * math.Sqrt(float64(n)/float64(n-1))
+}
+
+// tTable95 holds the two-sided 95% critical values of Student's t
+// distribution for 1 to 30 degrees of freedom.
+var tTable95 = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// tCritical95 returns the two-sided 95% critical value of Student's t
+// distribution for df degrees of freedom. Past the table df is rounded
+// down to the nearest of 30, 40, 60, 120 and 1000, so the interval errs
+// wide, approaching the normal 1.96.
+func tCritical95(df int) float64 {
+	switch {
+	case df < 1:
+		return math.Inf(1)
+	case df <= len(tTable95):
+		return tTable95[df-1]
+	case df < 40:
+		return 2.042
+	case df < 60:
+		return 2.021
+	case df < 120:
+		return 2.000
+	case df < 1000:
+		return 1.980
+	default:
+		return 1.962
+	}
+}
+
+// meanMargin is the half-width of the 95% confidence interval of m, the
+// mean of samples, from the sample standard deviation and Student's t for
+// n-1 degrees of freedom, which stays honest at the -samples minimum where
+// the normal 1.96 is too narrow.
+func meanMargin(samples []float64, m float64) float64 {
+	n := len(samples)
+	return tCritical95(n-1) * sampleStdDev(samples, m) / math.Sqrt(float64(n))
+}
+
+// geomean is the geometric mean of positive values, the average that
+// weighs a 2x change equally whether it hits a 1µs or a 1ms benchmark.
+func geomean(values []float64) float64 {
+	sumLog := 0.0
+	for _, v := range values {
+		sumLog += math.Log(v)
+	}
+	return math.Exp(sumLog / float64(len(values)))
+}
+
+func sortedCopy(samples []float64) []float64 {
 	sorted := make([]float64, len(samples))
 	copy(sorted, samples)
 	sort.Float64s(sorted)
-	idx := int(float64(len(sorted)-1) * p)
-	return sorted[idx]
+	return sorted
 }
 
-func runBenchmark(name string, query rego.PreparedEvalQuery, input map[string]interface{}) BenchmarkResult {
-	ctx := context.Background()
-	const warmupIterations = 100
-	const sampleIterations = 1000
+// percentile returns the nearest-rank percentile p of samples already
+// sorted in ascending order, truncating the rank p*(n-1) to a sample. The
+// quartiles keep this convention so they stay comparable with earlier
+// results.
+func percentile(sorted []float64, p float64) float64 {
+	return sorted[int(p*float64(len(sorted)-1))]
+}
+
+// interpolatedPercentile is percentile with linear interpolation between
+// the two samples bracketing the rank p*(n-1), the "type 7" quantile that
+// NumPy uses by default. Truncating the rank makes high percentiles jump
+// between neighbouring samples from run to run; with 1000 samples p99
+// sits at rank 989.01 and blends samples 989 and 990.
+func interpolatedPercentile(sorted []float64, p float64) float64 {
+	rank := p * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	frac := rank - float64(lo)
+	return sorted[lo] + (sorted[hi]-sorted[lo])*frac
+}
+
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// trimOutliers drops samples outside 1.5×IQR of the quartiles, such as
+// evaluations interrupted by a GC pause. It expects samples already sorted
+// and returns a sorted sub-slice.
+func trimOutliers(sorted []float64) []float64 {
+	q1 := percentile(sorted, 0.25)
+	q3 := percentile(sorted, 0.75)
+	iqr := q3 - q1
+	lo := sort.SearchFloat64s(sorted, q1-1.5*iqr)
+	hi := sort.Search(len(sorted), func(i int) bool { return sorted[i] > q3+1.5*iqr })
+	return sorted[lo:hi]
+}
+
+// runOptions controls how runBenchmark measures and summarizes samples.
+type runOptions struct {
+	warmup       int
+	samples      int
+	trimOutliers bool
+	keepSamples  bool
+	// timeout bounds each benchmark; zero means no limit.
+	timeout time.Duration
+	// policyDir replaces the embedded suite with the policies in this
+	// directory; see dirBenchmarks.
+	policyDir string
+	// wasm adds the opa/wasm benchmarks.
+	wasm bool
+	// prepare adds the opa/prepare benchmarks.
+	prepare bool
+	// parallel is the number of embedded benchmarks run concurrently; 1
+	// runs them one after another.
+	parallel int
+	// inputPath is the JSON input document for every policyDir benchmark.
+	inputPath string
+	// filter selects the benchmarks to run by name; nil runs all of them.
+	filter *regexp.Regexp
+	// auto keeps sampling in batches of samples until the mean changes
+	// by less than autoThreshold between batches and its 95% confidence
+	// margin is within autoThreshold of it, or maxSamples is hit.
+	auto          bool
+	autoThreshold float64
+	maxSamples    int
+	// sizes adds quantifier and count benchmarks over generated user
+	// lists of each size.
+	sizes []int
+	// seed seeds the documents of the opa/random benchmarks.
+	seed int64
+	// onResult, if set, receives each result as its benchmark finishes.
+	onResult func(BenchmarkResult)
+	// console receives the progress output: stdout, or stderr when the
+	// results themselves go to stdout.
+	console io.Writer
+	// quiet suppresses the progress output.
+	quiet bool
+	// verbose prints a histogram of each benchmark's samples.
+	verbose bool
+	// progress reports the benchmarks of a run as they go; the runners
+	// set it once they know how many benchmarks they will run.
+	progress *progress
+}
+
+// sampleCap returns the most samples runBenchmark may collect.
+func (opts runOptions) sampleCap() int {
+	if opts.auto && opts.maxSamples > opts.samples {
+		return opts.maxSamples
+	}
+	return opts.samples
+}
+
+// selected returns the benchmarks in defs that opts.filter selects.
+func (opts runOptions) selected(defs []benchDef) []benchDef {
+	if opts.filter == nil {
+		return defs
+	}
+	var matched []benchDef
+	for _, d := range defs {
+		if opts.filter.MatchString(d.name) {
+			matched = append(matched, d)
+		}
+	}
+	return matched
+}
+
+// stoppedResult marks a benchmark whose context ended before it finished:
+// timed-out when it ran past runOptions.timeout, interrupted otherwise.
+// samples is how many samples it had collected.
+func stoppedResult(name string, err error, samples int) BenchmarkResult {
+	marker := "interrupted"
+	if errors.Is(err, context.DeadlineExceeded) {
+		marker = "timed-out"
+	}
+	return BenchmarkResult{
+		Name: name,
+		Results: map[string]interface{}{
+			marker:    true,
+			"samples": samples,
+		},
+	}
+}
+
+// measured reports whether r holds timing statistics, rather than marking
+// a benchmark that failed or was stopped early.
+func measured(r BenchmarkResult) bool {
+	_, ok := r.Results["mean-ns"]
+	return ok
+}
+
+// logf prints a progress message unless opts.quiet is set.
+func (opts runOptions) logf(format string, args ...interface{}) {
+	if !opts.quiet {
+		fmt.Fprintf(opts.console, format, args...)
+	}
+}
+
+// started begins the progress line of the named benchmark.
+func (opts runOptions) started(name string) {
+	opts.progress.start(name)
+}
+
+// finished ends the progress line of a benchmark with its outcome and
+// passes the result to opts.onResult.
+func (opts runOptions) finished(r BenchmarkResult) {
+	if opts.onResult != nil {
+		opts.onResult(r)
+	}
+	opts.progress.finish(r)
+}
+
+// transitionEvals is the number of evaluations runBenchmark times between
+// the warmup and the samples.
+const transitionEvals = 3
+
+// runBenchmark measures query against input. With runOptions.timeout set,
+// the benchmark is abandoned once it runs that long, and the result only
+// marks it timed-out; cancelling ctx marks it interrupted.
+func runBenchmark(ctx context.Context, name string, query rego.PreparedEvalQuery, input map[string]interface{}, opts runOptions) BenchmarkResult {
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	// Check the policy evaluates to a decision; otherwise we would only
+	// time an error path or an undefined rule. Being the first evaluation
+	// of the query on this input, it is also timed as cold-ns.
+	coldStart := time.Now()
+	rs, err := query.Eval(ctx, rego.EvalInput(input))
+	cold := time.Since(coldStart)
+	if err == nil && len(rs) == 0 {
+		err = errors.New("query produced no decision; is the rule defined?")
+	}
+	if err != nil {
+		return BenchmarkResult{Name: name, Results: map[string]interface{}{}, Error: err.Error()}
+	}
+
+	// The encoded input size lets ns-per-input-byte be compared across
+	// benchmarks; it is measured here so it stays out of the timings.
+	inputBytes, inputErr := json.Marshal(input)
 
 	// Warmup
-	for i := 0; i < warmupIterations; i++ {
+	for i := 0; i < opts.warmup; i++ {
 		query.Eval(ctx, rego.EvalInput(input))
+		if err := ctx.Err(); err != nil {
+			return stoppedResult(name, err, 0)
+		}
 	}
 
 	// Force GC before measurement
 	runtime.GC()
 
-	// Collect samples
-	samples := make([]float64, sampleIterations)
-	for i := 0; i < sampleIterations; i++ {
+	// The first evaluations after the GC run on a fresh heap and are
+	// timed apart from the samples as transition-ns, between the cold
+	// first evaluation and the steady state.
+	transition := make([]float64, 0, transitionEvals)
+	for i := 0; i < transitionEvals; i++ {
 		start := time.Now()
 		query.Eval(ctx, rego.EvalInput(input))
-		samples[i] = float64(time.Since(start).Nanoseconds())
+		transition = append(transition, float64(time.Since(start).Nanoseconds()))
+		if err := ctx.Err(); err != nil {
+			return stoppedResult(name, err, 0)
+		}
+	}
+
+	// Collect samples. Memory is snapshotted after warmup so allocations
+	// made while rego fills its caches are not attributed to the samples;
+	// the slice is sized for the largest run so appending never allocates.
+	samples := make([]float64, 0, opts.sampleCap())
+	sample := func(n int) error {
+		for i := 0; i < n; i++ {
+			start := time.Now()
+			query.Eval(ctx, rego.EvalInput(input))
+			samples = append(samples, float64(time.Since(start).Nanoseconds()))
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	err = sample(opts.samples)
+	// In auto mode, keep adding batches until one moves the running mean
+	// by less than autoThreshold and the mean is known to within it; a
+	// small first batch can look stable while its interval is still wide.
+	for prev := mean(samples); err == nil && opts.auto && len(samples) < opts.sampleCap(); {
+		err = sample(min(opts.samples, opts.sampleCap()-len(samples)))
+		cur := mean(samples)
+		if math.Abs(cur-prev)/prev < opts.autoThreshold && meanMargin(samples, cur)/cur < opts.autoThreshold {
+			break
+		}
+		prev = cur
+	}
+	if err != nil {
+		return stoppedResult(name, err, len(samples))
+	}
+	runtime.ReadMemStats(&after)
+
+	result := sampleResult(name, samples, &before, &after, opts)
+	result.Results["cold-ns"] = cold.Nanoseconds()
+	if m, ok := resultValue(result, "mean-ns"); ok && m > 0 {
+		result.Results["cold-ratio"] = float64(cold.Nanoseconds()) / m
 	}
+	result.Results["transition-ns"] = int64(mean(transition))
+	if inputErr == nil {
+		result.Results["input-bytes"] = len(inputBytes)
+	}
+	return result
+}
+
+// sampleResult summarizes samples, timed between the memory snapshots
+// before and after, into the statistics of a measured BenchmarkResult.
+func sampleResult(name string, samples []float64, before, after *runtime.MemStats, opts runOptions) BenchmarkResult {
+	sorted := sortedCopy(samples)
+	if opts.trimOutliers {
+		sorted = trimOutliers(sorted)
+	}
+	m := mean(sorted)
+	sd := stdDev(sorted, m)
+	// rel-margin is the margin of the 95% confidence interval as a
+	// percentage of the mean: two means that differ by less than it are
+	// not told apart.
+	stdErr := sampleStdDev(sorted, m) / math.Sqrt(float64(len(sorted)))
+	margin := meanMargin(sorted, m)
+
+	result := BenchmarkResult{
+		Name: name,
+		Results: map[string]interface{}{
+			"mean-ns":           int64(m),
+			"median-ns":         int64(median(sorted)),
+			"min-ns":            int64(sorted[0]),
+			"max-ns":            int64(sorted[len(sorted)-1]),
+			"std-dev":           int64(sd),
+			"cv":                sd / m,
+			"ci-95-low":         int64(m - margin),
+			"ci-95-high":        int64(m + margin),
+			"std-err":           int64(stdErr),
+			"rel-margin":        margin / m * 100,
+			"ops-per-sec":       1e9 / m,
+			"lower-q":           int64(percentile(sorted, 0.25)),
+			"upper-q":           int64(percentile(sorted, 0.75)),
+			"p90-ns":            int64(interpolatedPercentile(sorted, 0.90)),
+			"p95-ns":            int64(interpolatedPercentile(sorted, 0.95)),
+			"p99-ns":            int64(interpolatedPercentile(sorted, 0.99)),
+			"samples":           len(samples),
+			"outliers-removed":  len(samples) - len(sorted),
+			"gc-count":          int64(after.NumGC - before.NumGC),
+			"total-alloc-bytes": int64(after.TotalAlloc - before.TotalAlloc),
+			"bytes-per-op":      int64(after.TotalAlloc-before.TotalAlloc) / int64(len(samples)),
+			"allocs-per-op":     int64(after.Mallocs-before.Mallocs) / int64(len(samples)),
+		},
+	}
+	if opts.verbose {
+		result.sorted = sorted
+	}
+	if opts.keepSamples {
+		result.Samples = samples
+	}
+	return result
+}
+
+// geomeanName names the synthetic result summarizing the whole suite.
+const geomeanName = "opa/geomean"
+
+// summarySuffix ends the names of the synthetic per-category results, as
+// in opa/quantifier/_summary.
+const summarySuffix = "/_summary"
 
-	m := mean(samples)
-	sd := stdDev(samples, m)
+// synthetic reports whether r was computed from other results rather than
+// measured: the suite geomean or a category summary.
+func synthetic(r BenchmarkResult) bool {
+	return r.Name == geomeanName || strings.HasSuffix(r.Name, summarySuffix)
+}
+
+// suiteGeomean returns a synthetic result holding the geometric mean of
+// the mean-ns of results. Latencies across the suite span orders of
+// magnitude, so an arithmetic mean would only track the slowest
+// benchmarks.
+func suiteGeomean(results []BenchmarkResult) BenchmarkResult {
+	return geomeanResult(geomeanName, results)
+}
 
+// geomeanResult returns a synthetic result named name holding the
+// geometric mean of the mean-ns of the measured, non-synthetic results
+// and their count.
+func geomeanResult(name string, results []BenchmarkResult) BenchmarkResult {
+	var means []float64
+	for _, r := range results {
+		if synthetic(r) {
+			continue
+		}
+		if m, ok := resultValue(r, "mean-ns"); ok {
+			means = append(means, m)
+		}
+	}
 	return BenchmarkResult{
 		Name: name,
 		Results: map[string]interface{}{
-			"mean-ns":  int64(m),
-			"std-dev":  int64(sd),
-			"lower-q":  int64(percentile(samples, 0.25)),
-			"upper-q":  int64(percentile(samples, 0.75)),
-			"samples":  sampleIterations,
-			"gc-count": nil,
+			"mean-ns":    int64(geomean(means)),
+			"benchmarks": len(means),
 		},
 	}
 }
 
+// categorySummaries returns a <category>/_summary result for every
+// category of results with a measured benchmark, in order of first
+// appearance, so a regression of a whole category such as opa/quantifier
+// shows as one line.
+func categorySummaries(results []BenchmarkResult) []BenchmarkResult {
+	var order []string
+	byCategory := make(map[string][]BenchmarkResult)
+	for _, r := range results {
+		if synthetic(r) || !measured(r) {
+			continue
+		}
+		c := category(r.Name)
+		if _, ok := byCategory[c]; !ok {
+			order = append(order, c)
+		}
+		byCategory[c] = append(byCategory[c], r)
+	}
+	summaries := make([]BenchmarkResult, 0, len(order))
+	for _, c := range order {
+		summaries = append(summaries, geomeanResult(c+summarySuffix, byCategory[c]))
+	}
+	return summaries
+}
+
+// withSummaries appends the categorySummaries and the suiteGeomean of
+// results, unless no benchmark in results was measured.
+func withSummaries(results []BenchmarkResult) []BenchmarkResult {
+	for _, r := range results {
+		if measured(r) {
+			results = append(results, categorySummaries(results)...)
+			return append(results, suiteGeomean(results))
+		}
+	}
+	return results
+}
+
+// aggregateRuns combines the results of repeated runAllBenchmarks passes.
+// The measured results of each benchmark have their raw samples pooled
+// and every statistic recomputed from the pool, so percentiles and the
+// confidence interval describe the pooled distribution rather than an
+// average of per-run values, provided the runs kept their samples; without
+// them only the mean is reported. run-variance holds
+// the std-dev of the per-run means: the noise between runs, as opposed to
+// std-dev within one run. Allocation and other per-run figures are
+// averaged, and runs counts only the runs that measured the benchmark.
+// A benchmark no run measured keeps its first result, such as an error
+// or an interruption. The synthetic summaries are recomputed.
+func aggregateRuns(runs [][]BenchmarkResult, opts runOptions) []BenchmarkResult {
+	switch len(runs) {
+	case 0:
+		return nil
+	case 1:
+		return runs[0]
+	}
+
+	var order []string
+	byName := make(map[string][]BenchmarkResult)
+	for _, run := range runs {
+		for _, r := range run {
+			if synthetic(r) {
+				continue
+			}
+			if _, ok := byName[r.Name]; !ok {
+				order = append(order, r.Name)
+			}
+			byName[r.Name] = append(byName[r.Name], r)
+		}
+	}
+
+	var results []BenchmarkResult
+	for _, name := range order {
+		var rs []BenchmarkResult
+		for _, r := range byName[name] {
+			if measured(r) {
+				rs = append(rs, r)
+			}
+		}
+		if len(rs) == 0 {
+			results = append(results, byName[name][0])
+			continue
+		}
+		results = append(results, poolRuns(name, rs, opts))
+	}
+	return withSummaries(results)
+}
+
+// poolRuns combines the measured results rs of one benchmark; see
+// aggregateRuns.
+func poolRuns(name string, rs []BenchmarkResult, opts runOptions) BenchmarkResult {
+	agg := BenchmarkResult{Name: name, Results: make(map[string]interface{})}
+	var pooled, means []float64
+	for _, r := range rs {
+		pooled = append(pooled, r.Samples...)
+		if m, ok := resultValue(r, "mean-ns"); ok {
+			means = append(means, m)
+		}
+		for key, v := range r.Results {
+			if _, ok := agg.Results[key]; ok {
+				continue
+			}
+			values := make([]float64, 0, len(rs))
+			for _, r := range rs {
+				if f, ok := resultValue(r, key); ok {
+					values = append(values, f)
+				}
+			}
+			switch v.(type) {
+			case int64:
+				agg.Results[key] = int64(mean(values))
+			case int:
+				agg.Results[key] = int(mean(values))
+			case float64:
+				agg.Results[key] = mean(values)
+			default:
+				agg.Results[key] = v
+			}
+		}
+	}
+
+	if len(pooled) > 0 {
+		var mem runtime.MemStats
+		stats := sampleResult(name, pooled, &mem, &mem, opts)
+		for _, key := range pooledKeys {
+			agg.Results[key] = stats.Results[key]
+		}
+		agg.Samples = pooled
+		agg.sorted = stats.sorted
+	} else {
+		// Without samples the distribution is unknown; only the mean and
+		// the noise between runs are reported.
+		for _, key := range pooledKeys {
+			delete(agg.Results, key)
+		}
+		m := mean(means)
+		agg.Results["mean-ns"] = int64(m)
+		agg.Results["ops-per-sec"] = 1e9 / m
+	}
+	agg.Results["run-variance"] = int64(stdDev(means, mean(means)))
+	agg.Results["runs"] = len(rs)
+	return agg
+}
+
+// pooledKeys are the results sampleResult derives from the distribution of
+// the samples, which aggregateRuns recomputes from the pooled samples
+// rather than averaging.
+var pooledKeys = []string{
+	"mean-ns", "median-ns", "min-ns", "max-ns", "std-dev", "cv",
+	"ci-95-low", "ci-95-high", "std-err", "rel-margin", "ops-per-sec",
+	"lower-q", "upper-q", "p90-ns", "p95-ns", "p99-ns",
+	"samples", "outliers-removed",
+}
+
 type benchDef struct {
 	name   string
 	policy string
 	doc    map[string]interface{}
+	// expected is the decision the policy must reach for doc; see
+	// checkDecision.
+	expected bool
+	// query overrides the rego query of the benchmark, for policies with
+	// another entrypoint such as data.authz.main.decision. Empty uses the
+	// group's convention for policy.
+	query string
+	// data is the data document the policy is prepared with; nil prepares
+	// it without one.
+	data map[string]interface{}
 }
 
-func runAllBenchmarks() ([]BenchmarkResult, error) {
-	fmt.Println("Preparing policies...")
-	prepared, err := preparePolicies()
-	if err != nil {
-		return nil, err
+// queryFor returns the prepared query of b: prepared[b.policy], or when b
+// sets its own query, the embedded filename prepared with that query.
+func queryFor(b benchDef, prepared map[string]rego.PreparedEvalQuery, filename string) (rego.PreparedEvalQuery, error) {
+	if b.query == "" {
+		return prepared[b.policy], nil
 	}
+	p, err := prepareQuery(b.name, filename, b.query)
+	return p.Query, err
+}
 
-	policyMap := make(map[string]rego.PreparedEvalQuery)
-	for _, p := range prepared {
-		policyMap[p.Name] = p.Query
+var plainBenchmarks = []benchDef{
+	{"opa/simple-satisfied", "simple", docSimpleSatisfied, true, "", nil},
+	{"opa/simple-contradicted", "simple", docSimpleContradicted, false, "", nil},
+	{"opa/medium-satisfied", "medium", docMediumSatisfied, true, "", nil},
+	{"opa/medium-partial", "medium", docMediumPartial, false, "", nil},
+	{"opa/complex-satisfied", "complex", docComplexSatisfied, true, "", nil},
+	{"opa/complex-partial", "complex", docComplexPartial, false, "", nil},
+}
+
+var quantifierBenchmarks = []benchDef{
+	{"opa/quantifier/forall-small-satisfied", "forall_simple", docUsers5AllActive, true, "", nil},
+	{"opa/quantifier/forall-small-contradicted", "forall_simple", docUsers5OneInactive, false, "", nil},
+	{"opa/quantifier/forall-medium-satisfied", "forall_nested", docUsers20AllVerified, true, "", nil},
+	{"opa/quantifier/forall-medium-contradicted-early", "forall_nested", docUsers20FirstUnverified, false, "", nil},
+	{"opa/quantifier/forall-medium-contradicted-late", "forall_nested", docUsers20LastUnverified, false, "", nil},
+	{"opa/quantifier/forall-large-satisfied", "forall_simple", docUsers100AllActive, true, "", nil},
+	{"opa/quantifier/forall-large-contradicted-early", "forall_simple", docUsers100FirstInactive, false, "", nil},
+	{"opa/quantifier/forall-large-contradicted-late", "forall_simple", docUsers100LastInactive, false, "", nil},
+	{"opa/quantifier/exists-small-satisfied", "exists_simple", docUsers5FirstAdmin, true, "", nil},
+	{"opa/quantifier/exists-small-contradicted", "exists_simple", docUsers5NoAdmin, false, "", nil},
+	{"opa/quantifier/exists-large-early-exit", "exists_simple", docUsers100FirstAdmin, true, "", nil},
+	{"opa/quantifier/exists-large-late-exit", "exists_simple", docUsers100LastAdmin, true, "", nil},
+	{"opa/quantifier/exists-large-contradicted", "exists_simple", docUsers100NoAdmin, false, "", nil},
+	{"opa/quantifier/nested-satisfied", "nested_forall_exists", docTeamsAllHaveLead, true, "", nil},
+	{"opa/quantifier/nested-contradicted", "nested_forall_exists", docTeamsOneMissingLead, false, "", nil},
+}
+
+var countBenchmarks = []benchDef{
+	{"opa/count/simple-5-satisfied", "count_simple", docUsers5AllActive, true, "", nil},
+	{"opa/count/simple-5-contradicted", "count_simple", map[string]interface{}{"users": makeUsers(3, true)}, false, "", nil},
+	{"opa/count/medium-20-satisfied", "count_medium", docUsers20AllVerified, true, "", nil},
+	{"opa/count/medium-20-contradicted", "count_medium", map[string]interface{}{"users": makeUsers(19, true)}, false, "", nil},
+	{"opa/count/large-100-satisfied", "count_large", docUsers100AllActive, true, "", nil},
+	{"opa/count/large-100-contradicted", "count_large", map[string]interface{}{"users": makeUsers(99, true)}, false, "", nil},
+	{"opa/count/nested-path", "count_nested", docOrgWithMembers, true, "", nil},
+	{"opa/count/nested-path-contradicted", "count_nested", docOrgFewMembers, false, "", nil},
+	{"opa/count/with-comparison", "count_with_comparison", map[string]interface{}{
+		"users":  makeUsers(5, true),
+		"active": true,
+	}, true, "", nil},
+	{"opa/count/with-comparison-contradicted", "count_with_comparison", map[string]interface{}{
+		"users":  makeUsers(5, true),
+		"active": false,
+	}, false, "", nil},
+}
+
+var filteredBenchmarks = []benchDef{
+	// Forall with filter
+	{"opa/filtered/forall-small-satisfied", "forall_filtered", docUsers5AllActiveVerified, true, "", nil},
+	{"opa/filtered/forall-small-mixed", "forall_filtered", docUsers5MixedActive, true, "", nil},
+	{"opa/filtered/forall-small-contradicted", "forall_filtered", docUsers5ActiveOneUnverified, false, "", nil},
+	{"opa/filtered/forall-medium", "forall_filtered", docUsers20HalfActive, true, "", nil},
+	{"opa/filtered/forall-large", "forall_filtered", docUsers100MostlyActive, true, "", nil},
+	// Exists with filter
+	{"opa/filtered/exists-small-satisfied", "exists_filtered", docUsers5ActiveWithAdmin, true, "", nil},
+	{"opa/filtered/exists-small-contradicted", "exists_filtered", docUsers5ActiveNoAdmin, false, "", nil},
+	{"opa/filtered/exists-large-early", "exists_filtered", docUsers100ActiveFirstAdmin, true, "", nil},
+	{"opa/filtered/exists-large-late", "exists_filtered", docUsers100ActiveLastAdmin, true, "", nil},
+	// Count with filter
+	{"opa/filtered/count-simple", "count_filtered", docUsers5MixedActive, true, "", nil},
+	{"opa/filtered/count-simple-contradicted", "count_filtered", docUsers5MostlyInactive, false, "", nil},
+	{"opa/filtered/count-medium", "count_filtered", docUsers20HalfActive, true, "", nil},
+	{"opa/filtered/count-large", "count_filtered", docUsers100MostlyActive, true, "", nil},
+	{"opa/filtered/count-complex", "count_filtered_complex", docUsers100MostlyActive, true, "", nil},
+	{"opa/filtered/count-complex-contradicted", "count_filtered_complex", docUsers100ActiveLowScore, false, "", nil},
+	// Nested with filter
+	{"opa/filtered/nested-satisfied", "nested_filtered", docTeams5ActiveWithLeads, true, "", nil},
+	{"opa/filtered/nested-contradicted", "nested_filtered", docTeams5ActiveMissingLead, false, "", nil},
+}
+
+// sizedQuantifierBenchmarks returns forall and late-exit exists
+// benchmarks over n generated users for each n in sizes, so evaluation
+// time can be plotted against collection size.
+func sizedQuantifierBenchmarks(sizes []int) []benchDef {
+	var defs []benchDef
+	for _, n := range sizes {
+		defs = append(defs,
+			benchDef{fmt.Sprintf("opa/quantifier/forall-large-%d-satisfied", n), "forall_simple",
+				map[string]interface{}{"users": makeUsers(n, true)}, true, "", nil},
+			benchDef{fmt.Sprintf("opa/quantifier/exists-large-%d-late-exit", n), "exists_simple",
+				map[string]interface{}{"users": makeUsersWithAdmin(n, n-1)}, true, "", nil},
+		)
 	}
+	return defs
+}
 
-	benchmarks := []benchDef{
-		{"opa/simple-satisfied", "simple", docSimpleSatisfied},
-		{"opa/simple-contradicted", "simple", docSimpleContradicted},
-		{"opa/medium-satisfied", "medium", docMediumSatisfied},
-		{"opa/medium-partial", "medium", docMediumPartial},
-		{"opa/complex-satisfied", "complex", docComplexSatisfied},
-		{"opa/complex-partial", "complex", docComplexPartial},
+// sizedCountBenchmarks returns count and filtered count benchmarks over n
+// generated users for each n in sizes. count_large needs 100 users, so
+// smaller sizes are contradicted. Sizes the fixed suite already covers
+// are skipped.
+func sizedCountBenchmarks(sizes []int) []benchDef {
+	fixed := make(map[string]bool, len(countBenchmarks))
+	for _, b := range countBenchmarks {
+		fixed[b.name] = true
 	}
+	var defs []benchDef
+	for _, n := range sizes {
+		outcome := "satisfied"
+		if n < 100 {
+			outcome = "contradicted"
+		}
+		name := fmt.Sprintf("opa/count/large-%d-%s", n, outcome)
+		if !fixed[name] {
+			defs = append(defs, benchDef{name, "count_large",
+				map[string]interface{}{"users": makeUsers(n, true)}, n >= 100, "", nil})
+		}
+		defs = append(defs, benchDef{fmt.Sprintf("opa/count/filtered-%d", n), "count_filtered",
+			map[string]interface{}{"users": makeUsersWithActiveAndProfile(n, true, true, "user", 90)}, n >= 3, "", nil})
+	}
+	return defs
+}
 
-	fmt.Println("Running benchmarks...")
-	var results []BenchmarkResult
-	for _, b := range benchmarks {
-		fmt.Printf("  %s...", b.name)
-		result := runBenchmark(b.name, policyMap[b.policy], b.doc)
-		results = append(results, result)
-		fmt.Printf(" %d ns\n", result.Results["mean-ns"])
+// randomRules are the count_filter.rego rules run against random users,
+// each with a Go mirror of the rule that supplies the expected decision.
+var randomRules = []struct {
+	name   string
+	policy string
+	decide func(users []map[string]interface{}) bool
+}{
+	{"forall-filtered", "forall_filtered", func(users []map[string]interface{}) bool {
+		for _, u := range users {
+			if u["active"] == true && u["profile"].(map[string]interface{})["verified"] != true {
+				return false
+			}
+		}
+		return true
+	}},
+	{"exists-filtered", "exists_filtered", func(users []map[string]interface{}) bool {
+		for _, u := range users {
+			if u["active"] == true && u["role"] == "admin" {
+				return true
+			}
+		}
+		return false
+	}},
+	{"count-filtered", "count_filtered", func(users []map[string]interface{}) bool {
+		n := 0
+		for _, u := range users {
+			if u["active"] == true {
+				n++
+			}
+		}
+		return n >= 3
+	}},
+	{"count-complex", "count_filtered_complex", func(users []map[string]interface{}) bool {
+		n := 0
+		for _, u := range users {
+			if u["active"] == true && u["score"].(int) > 80 {
+				n++
+			}
+		}
+		return n >= 2
+	}},
+}
+
+// randomBenchmarks returns the filtered binding rules run against 20 and
+// 100 users from makeRandomUsers. The same seed yields the same documents,
+// so runs stay comparable while still taking the branches that the
+// uniform documents never reach.
+func randomBenchmarks(seed int64) []benchDef {
+	rng := rand.New(rand.NewSource(seed))
+	var defs []benchDef
+	for _, n := range []int{20, 100} {
+		users := makeRandomUsers(n, rng)
+		doc := map[string]interface{}{"users": users}
+		for _, r := range randomRules {
+			defs = append(defs, benchDef{fmt.Sprintf("opa/random/%s-%d", r.name, n), r.policy, doc, r.decide(users), "", nil})
+		}
+	}
+	return defs
+}
+
+// nestedPathBenchmarks read one value at each of nestedDepths, isolating
+// the cost of key traversal from that of iteration.
+var nestedPathBenchmarks = func() []benchDef {
+	var defs []benchDef
+	for _, depth := range nestedDepths {
+		defs = append(defs, benchDef{fmt.Sprintf("opa/nested-path/depth-%d", depth),
+			fmt.Sprintf("depth_%d", depth), makeNestedDoc(depth), true, "", nil})
+	}
+	return defs
+}()
+
+// wideBenchmarks read the last key of documents with each of wideKeys
+// top-level keys, measuring how binding the input scales with its width.
+var wideBenchmarks = func() []benchDef {
+	var defs []benchDef
+	for _, keys := range wideKeys {
+		defs = append(defs, benchDef{fmt.Sprintf("opa/wide/keys-%d", keys),
+			fmt.Sprintf("keys_%d", keys), makeWideDoc(keys), true, "", nil})
+	}
+	return defs
+}()
+
+// dataBenchmarks look input up in the 10k-user dataDirectory, measuring
+// the cost of reading data from the store: a keyed lookup of one user,
+// and a scan of the grants matching the first user, the last or none.
+var dataBenchmarks = []benchDef{
+	{"opa/data/user-active-satisfied", "user_active", map[string]interface{}{"user": "user1"}, true, "", dataDirectory},
+	{"opa/data/user-inactive", "user_active", map[string]interface{}{"user": "user0"}, false, "", dataDirectory},
+	{"opa/data/user-missing", "user_active", map[string]interface{}{"user": "nobody"}, false, "", dataDirectory},
+	{"opa/data/grant-first-satisfied", "role_granted", map[string]interface{}{"user": "user0", "role": "reader"}, true, "", dataDirectory},
+	{"opa/data/grant-last-satisfied", "role_granted", map[string]interface{}{"user": fmt.Sprintf("user%d", directorySize-1), "role": "reader"}, true, "", dataDirectory},
+	{"opa/data/grant-missing", "role_granted", map[string]interface{}{"user": "nobody", "role": "reader"}, false, "", dataDirectory},
+}
+
+// membershipSizes are the allowlist sizes of membershipBenchmarks.
+var membershipSizes = []int{100, 10000}
+
+// membershipBenchmarks test input.user for membership in an allowlist of
+// each of membershipSizes, collected into a set and as the array itself,
+// for the first user, the last and one not listed. The set is collected
+// anew on every evaluation, so its benchmarks also time building it.
+var membershipBenchmarks = func() []benchDef {
+	var defs []benchDef
+	for _, n := range membershipSizes {
+		data := makeAllowlist(n)
+		for _, kind := range []string{"set", "array"} {
+			rule := kind + "_member"
+			prefix := fmt.Sprintf("opa/membership/%s-%d", kind, n)
+			defs = append(defs,
+				benchDef{prefix + "-hit-first", rule, map[string]interface{}{"user": "user0"}, true, "", data},
+				benchDef{prefix + "-hit-last", rule, map[string]interface{}{"user": fmt.Sprintf("user%d", n-1)}, true, "", data},
+				benchDef{prefix + "-miss", rule, map[string]interface{}{"user": "nobody"}, false, "", data},
+			)
+		}
 	}
+	return defs
+}()
+
+// regexSegments are the path lengths, in segments, of regexBenchmarks.
+var regexSegments = []int{1, 10, 100}
+
+// regexBenchmarks match API paths of each of regexSegments against the
+// pattern of regex.rego, and paths that fail to match at their last
+// character.
+var regexBenchmarks = func() []benchDef {
+	var defs []benchDef
+	for _, n := range regexSegments {
+		defs = append(defs,
+			benchDef{fmt.Sprintf("opa/regex/match-%d-segments", n), "path_allowed",
+				map[string]interface{}{"path": makeAPIPath(n, true)}, true, "", nil},
+			benchDef{fmt.Sprintf("opa/regex/miss-%d-segments", n), "path_allowed",
+				map[string]interface{}{"path": makeAPIPath(n, false)}, false, "", nil},
+		)
+	}
+	return defs
+}()
+
+// benchGroup is a category of benchmarks, as listed by -list.
+type benchGroup struct {
+	category   string
+	benchmarks []benchDef
+}
+
+var benchGroups = []benchGroup{
+	{"plain", plainBenchmarks},
+	{"partial", partialBenchmarks},
+	{"wasm", wasmBenchmarks},
+	{"quantifier", quantifierBenchmarks},
+	{"count", countBenchmarks},
+	{"filtered", filteredBenchmarks},
+	{"nested-path", nestedPathBenchmarks},
+	{"wide", wideBenchmarks},
+	{"data", dataBenchmarks},
+	{"membership", membershipBenchmarks},
+	{"regex", regexBenchmarks},
+	{"prepare", prepareBenchmarks},
+}
 
-	// Run quantifier benchmarks
-	fmt.Println("Preparing quantifier policies...")
-	quantifierPolicies, err := prepareQuantifierPolicies()
+// groups returns benchGroups plus the benchmarks generated from opts.seed
+// and opts.sizes.
+func (opts runOptions) groups() []benchGroup {
+	groups := append(benchGroups[:len(benchGroups):len(benchGroups)],
+		benchGroup{"random", randomBenchmarks(opts.seed)})
+	if len(opts.sizes) == 0 {
+		return groups
+	}
+	return append(groups,
+		benchGroup{"sized quantifier", sizedQuantifierBenchmarks(opts.sizes)},
+		benchGroup{"sized count", sizedCountBenchmarks(opts.sizes)},
+	)
+}
+
+// available returns the benchmarks a run with opts can select from: those
+// of opts.policyDir when it is set, otherwise the embedded groups.
+func (opts runOptions) available() ([]benchGroup, error) {
+	if opts.policyDir == "" {
+		return opts.groups(), nil
+	}
+	defs, err := dirBenchmarks(opts.policyDir, opts.inputPath)
 	if err != nil {
 		return nil, err
 	}
+	return []benchGroup{{opts.policyDir, defs}}, nil
+}
+
+// hasBenchmark reports whether one of groups holds a benchmark named name.
+func hasBenchmark(groups []benchGroup, name string) bool {
+	for _, g := range groups {
+		for _, b := range g.benchmarks {
+			if b.name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// printBenchmarkList prints the benchmark names of every group, sorted
+// within each group.
+func printBenchmarkList(w io.Writer, groups []benchGroup) {
+	for _, g := range groups {
+		names := make([]string, len(g.benchmarks))
+		for i, b := range g.benchmarks {
+			names[i] = b.name
+		}
+		sort.Strings(names)
+		fmt.Fprintf(w, "%s:\n", g.category)
+		for _, name := range names {
+			fmt.Fprintf(w, "  %s\n", name)
+		}
+	}
+}
+
+// noMatchError reports that opts.filter selected none of groups.
+func noMatchError(opts runOptions, groups []benchGroup) error {
+	var available strings.Builder
+	printBenchmarkList(&available, groups)
+	return fmt.Errorf("no benchmark matches -filter %q; available benchmarks:\n%s",
+		opts.filter, strings.TrimRight(available.String(), "\n"))
+}
 
-	quantifierMap := make(map[string]rego.PreparedEvalQuery)
-	for _, p := range quantifierPolicies {
-		quantifierMap[p.Name] = p.Query
+// checkDecision evaluates b once and returns an error unless its decision
+// is b.expected, so a misspelled rule that is always undefined, or a
+// document that no longer exercises the intended path, fails before
+// anything is timed. The decision is the value of the first expression
+// of the first result, as inspected in check_result.go.
+func checkDecision(ctx context.Context, b benchDef, query rego.PreparedEvalQuery) error {
+	rs, err := query.Eval(ctx, rego.EvalInput(b.doc))
+	if err != nil {
+		return fmt.Errorf("validating %s: %w", b.name, err)
+	}
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		return fmt.Errorf("validating %s: no decision, expected %v", b.name, b.expected)
+	}
+	if decision := rs[0].Expressions[0].Value; decision != b.expected {
+		return fmt.Errorf("validating %s: decision is %v, expected %v", b.name, decision, b.expected)
 	}
+	return nil
+}
+
+// driftThreshold is the relative change in mean-ns of the first benchmark
+// over a run beyond which the machine is taken to have drifted, for
+// example by thermal throttling.
+const driftThreshold = 0.20
 
-	quantifierBenchmarks := []benchDef{
-		{"opa/quantifier/forall-small-satisfied", "forall_simple", docUsers5AllActive},
-		{"opa/quantifier/forall-small-contradicted", "forall_simple", docUsers5OneInactive},
-		{"opa/quantifier/forall-medium-satisfied", "forall_nested", docUsers20AllVerified},
-		{"opa/quantifier/forall-large-satisfied", "forall_simple", docUsers100AllActive},
-		{"opa/quantifier/exists-small-satisfied", "exists_simple", docUsers5FirstAdmin},
-		{"opa/quantifier/exists-small-contradicted", "exists_simple", docUsers5NoAdmin},
-		{"opa/quantifier/exists-large-early-exit", "exists_simple", docUsers100FirstAdmin},
-		{"opa/quantifier/exists-large-late-exit", "exists_simple", docUsers100LastAdmin},
-		{"opa/quantifier/nested-satisfied", "nested_forall_exists", docTeamsAllHaveLead},
-		{"opa/quantifier/nested-contradicted", "nested_forall_exists", docTeamsOneMissingLead},
+// driftBaseline is the first benchmark of a run and its result.
+type driftBaseline struct {
+	b      benchDef
+	query  rego.PreparedEvalQuery
+	result BenchmarkResult
+}
+
+// checkDrift re-runs the first benchmark of a run and returns the ratio of
+// its new mean-ns to the first, warning when the two differ by more than
+// driftThreshold: a machine that heats up or throttles during the run
+// slows the later benchmarks. It returns 0 if the re-run was not measured.
+func checkDrift(ctx context.Context, opts runOptions, first driftBaseline) float64 {
+	opts.logf("Re-running %s to check for drift...\n", first.b.name)
+	opts.keepSamples, opts.verbose = false, false
+	rerun := runBenchmark(ctx, first.b.name, first.query, first.b.doc, opts)
+	before, _ := resultValue(first.result, "mean-ns")
+	after, ok := resultValue(rerun, "mean-ns")
+	if !ok || before <= 0 {
+		return 0
+	}
+	drift := after / before
+	if math.Abs(drift-1) > driftThreshold {
+		fmt.Fprintf(os.Stderr, "\nWARNING: %s went from %.0f ns at the start of the run to %.0f ns at the end (%+.0f%%).\n"+
+			"The machine was not in a steady state (thermal throttling or background load?); results may be unreliable.\n\n",
+			first.b.name, before, after, (drift-1)*100)
 	}
+	return drift
+}
 
-	fmt.Println("Running quantifier benchmarks...")
-	for _, b := range quantifierBenchmarks {
-		fmt.Printf("  %s...", b.name)
-		result := runBenchmark(b.name, quantifierMap[b.policy], b.doc)
-		results = append(results, result)
-		fmt.Printf(" %d ns\n", result.Results["mean-ns"])
+// suiteGroup is a group of embedded benchmarks with the prepared query
+// each of them times.
+type suiteGroup struct {
+	label      string
+	benchmarks []benchDef
+	query      func(b benchDef) (rego.PreparedEvalQuery, error)
+}
+
+// runAllBenchmarks runs the selected benchmarks, after checking every one
+// of them reaches its expected decision. Once ctx is cancelled it stops
+// and returns the results collected so far. It also returns the drift of
+// the first benchmark over the run, as measured by checkDrift, or 0 when
+// that was not measured.
+func runAllBenchmarks(ctx context.Context, opts runOptions) ([]BenchmarkResult, float64, error) {
+	if opts.policyDir != "" {
+		results, err := runDirBenchmarks(ctx, opts)
+		return results, 0, err
 	}
 
-	// Run count and filtered benchmarks
-	fmt.Println("Preparing count/filter policies...")
-	countFilterPolicies, err := prepareCountFilterPolicies()
+	opts.logf("Preparing policies...\n")
+	policyMap, err := preparedMap(preparePolicies)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+	quantifierMap, err := preparedMap(prepareQuantifierPolicies)
+	if err != nil {
+		return nil, 0, err
+	}
+	countFilterMap, err := preparedMap(prepareCountFilterPolicies)
+	if err != nil {
+		return nil, 0, err
+	}
+	nestedMap, err := preparedMap(prepareNestedPolicies)
+	if err != nil {
+		return nil, 0, err
+	}
+	wideMap, err := preparedMap(prepareWidePolicies)
+	if err != nil {
+		return nil, 0, err
+	}
+	regexMap, err := preparedMap(prepareRegexPolicies)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	countFilterMap := make(map[string]rego.PreparedEvalQuery)
-	for _, p := range countFilterPolicies {
-		countFilterMap[p.Name] = p.Query
+	partialMap := make(map[string]rego.PreparedEvalQuery)
+	partialQuery := func(b benchDef) (rego.PreparedEvalQuery, error) {
+		if q, ok := partialMap[b.policy]; ok {
+			return q, nil
+		}
+		query := b.query
+		if query == "" {
+			query = "data.policy." + b.policy + ".allow"
+		}
+		p, err := preparePartialQuery(b.policy, b.policy+".rego", query)
+		if err != nil {
+			return rego.PreparedEvalQuery{}, err
+		}
+		partialMap[b.policy] = p.Query
+		return p.Query, nil
 	}
 
-	countBenchmarks := []benchDef{
-		{"opa/count/simple-5-satisfied", "count_simple", docUsers5AllActive},
-		{"opa/count/simple-5-contradicted", "count_simple", map[string]interface{}{"users": makeUsers(3, true)}},
-		{"opa/count/medium-20-satisfied", "count_medium", docUsers20AllVerified},
-		{"opa/count/large-100-satisfied", "count_large", docUsers100AllActive},
-		{"opa/count/nested-path", "count_nested", docOrgWithMembers},
-		{"opa/count/with-comparison", "count_with_comparison", map[string]interface{}{
-			"users":  makeUsers(5, true),
-			"active": true,
+	groups := []suiteGroup{
+		{"benchmarks", plainBenchmarks, func(b benchDef) (rego.PreparedEvalQuery, error) {
+			return queryFor(b, policyMap, b.policy+".rego")
+		}},
+		{"partial evaluation benchmarks", partialBenchmarks, partialQuery},
+		{"quantifier benchmarks", quantifierBenchmarks, func(b benchDef) (rego.PreparedEvalQuery, error) {
+			return queryFor(b, quantifierMap, "quantifier.rego")
+		}},
+		{"count benchmarks", countBenchmarks, func(b benchDef) (rego.PreparedEvalQuery, error) {
+			return queryFor(b, countFilterMap, "count_filter.rego")
+		}},
+		{"filtered binding benchmarks", filteredBenchmarks, func(b benchDef) (rego.PreparedEvalQuery, error) {
+			return queryFor(b, countFilterMap, "count_filter.rego")
 		}},
+		{"nested path benchmarks", nestedPathBenchmarks, func(b benchDef) (rego.PreparedEvalQuery, error) {
+			return queryFor(b, nestedMap, "nested.rego")
+		}},
+		{"wide document benchmarks", wideBenchmarks, func(b benchDef) (rego.PreparedEvalQuery, error) {
+			return queryFor(b, wideMap, "wide.rego")
+		}},
+		{"data document benchmarks", dataBenchmarks, storeQueries("data_lookup.rego")},
+		{"membership benchmarks", membershipBenchmarks, storeQueries("membership.rego")},
+		{"regex benchmarks", regexBenchmarks, func(b benchDef) (rego.PreparedEvalQuery, error) {
+			return queryFor(b, regexMap, "regex.rego")
+		}},
+	}
+	groups = append(groups, suiteGroup{"random document benchmarks", randomBenchmarks(opts.seed), func(b benchDef) (rego.PreparedEvalQuery, error) {
+		return queryFor(b, countFilterMap, "count_filter.rego")
+	}})
+	if len(opts.sizes) > 0 {
+		groups = append(groups,
+			suiteGroup{"sized quantifier benchmarks", sizedQuantifierBenchmarks(opts.sizes), func(b benchDef) (rego.PreparedEvalQuery, error) {
+				return queryFor(b, quantifierMap, "quantifier.rego")
+			}},
+			suiteGroup{"sized count benchmarks", sizedCountBenchmarks(opts.sizes), func(b benchDef) (rego.PreparedEvalQuery, error) {
+				return queryFor(b, countFilterMap, "count_filter.rego")
+			}},
+		)
 	}
 
-	fmt.Println("Running count benchmarks...")
-	for _, b := range countBenchmarks {
-		fmt.Printf("  %s...", b.name)
-		result := runBenchmark(b.name, countFilterMap[b.policy], b.doc)
-		results = append(results, result)
-		fmt.Printf(" %d ns\n", result.Results["mean-ns"])
-	}
-
-	filteredBenchmarks := []benchDef{
-		// Forall with filter
-		{"opa/filtered/forall-small-satisfied", "forall_filtered", docUsers5AllActiveVerified},
-		{"opa/filtered/forall-small-mixed", "forall_filtered", docUsers5MixedActive},
-		{"opa/filtered/forall-medium", "forall_filtered", docUsers20HalfActive},
-		{"opa/filtered/forall-large", "forall_filtered", docUsers100MostlyActive},
-		// Exists with filter
-		{"opa/filtered/exists-small-satisfied", "exists_filtered", docUsers5ActiveWithAdmin},
-		{"opa/filtered/exists-small-contradicted", "exists_filtered", docUsers5ActiveNoAdmin},
-		{"opa/filtered/exists-large-early", "exists_filtered", docUsers100ActiveFirstAdmin},
-		{"opa/filtered/exists-large-late", "exists_filtered", docUsers100ActiveLastAdmin},
-		// Count with filter
-		{"opa/filtered/count-simple", "count_filtered", docUsers5MixedActive},
-		{"opa/filtered/count-medium", "count_filtered", docUsers20HalfActive},
-		{"opa/filtered/count-large", "count_filtered", docUsers100MostlyActive},
-		{"opa/filtered/count-complex", "count_filtered_complex", docUsers100MostlyActive},
-		// Nested with filter
-		{"opa/filtered/nested-satisfied", "nested_filtered", docTeams5ActiveWithLeads},
-		{"opa/filtered/nested-contradicted", "nested_filtered", docTeams5ActiveMissingLead},
-	}
-
-	fmt.Println("Running filtered binding benchmarks...")
-	for _, b := range filteredBenchmarks {
-		fmt.Printf("  %s...", b.name)
-		result := runBenchmark(b.name, countFilterMap[b.policy], b.doc)
-		results = append(results, result)
-		fmt.Printf(" %d ns\n", result.Results["mean-ns"])
-	}
-
-	return results, nil
+	opts.logf("Validating decisions...\n")
+	for _, g := range groups {
+		for _, b := range opts.selected(g.benchmarks) {
+			query, err := g.query(b)
+			if err != nil {
+				return nil, 0, err
+			}
+			if err := checkDecision(ctx, b, query); err != nil {
+				// Interrupted mid-validation: end the run like an interrupt
+				// while sampling, so the results of earlier runs are kept.
+				if ctx.Err() != nil {
+					return nil, 0, nil
+				}
+				return nil, 0, err
+			}
+		}
+	}
+
+	total := 0
+	for _, g := range groups {
+		total += len(opts.selected(g.benchmarks))
+	}
+	if opts.wasm && wasmAvailable {
+		total += len(opts.selected(wasmBenchmarks))
+	}
+	if opts.prepare {
+		total += len(opts.selected(prepareBenchmarks))
+	}
+	opts.progress = newProgress(opts.console, total, opts.quiet, opts.verbose)
+	defer opts.progress.end()
+
+	var results []BenchmarkResult
+	var first *driftBaseline
+	if opts.parallel > 1 {
+		var jobs []benchJob
+		for _, g := range groups {
+			for _, b := range opts.selected(g.benchmarks) {
+				query, err := g.query(b)
+				if err != nil {
+					return nil, 0, err
+				}
+				jobs = append(jobs, benchJob{b, query})
+			}
+		}
+		opts.progress.section(fmt.Sprintf("%d benchmarks on %d workers", len(jobs), opts.parallel))
+		results = runParallel(ctx, jobs, opts)
+		// The drift check re-runs the first job, as a serial run would.
+		for _, j := range jobs {
+			i := slices.IndexFunc(results, func(r BenchmarkResult) bool { return r.Name == j.b.name })
+			if i >= 0 && measured(results[i]) {
+				first = &driftBaseline{j.b, j.query, results[i]}
+				break
+			}
+		}
+	} else {
+		for _, g := range groups {
+			opts.progress.section(g.label)
+			for _, b := range opts.selected(g.benchmarks) {
+				if ctx.Err() != nil {
+					break
+				}
+				query, err := g.query(b)
+				if err != nil {
+					return nil, 0, err
+				}
+				opts.started(b.name)
+				result := runBenchmark(ctx, b.name, query, b.doc, opts)
+				results = append(results, result)
+				opts.finished(result)
+				if first == nil && measured(result) {
+					first = &driftBaseline{b, query, result}
+				}
+			}
+		}
+	}
+
+	if opts.wasm {
+		wasmResults, err := runWasmBenchmarks(ctx, opts, results)
+		if err != nil {
+			return nil, 0, err
+		}
+		results = append(results, wasmResults...)
+	}
+
+	if opts.prepare {
+		results = append(results, runPrepareBenchmarks(ctx, opts)...)
+	}
+
+	if len(results) == 0 && ctx.Err() == nil {
+		return nil, 0, noMatchError(opts, opts.groups())
+	}
+
+	drift := 0.0
+	if first != nil && ctx.Err() == nil {
+		opts.progress.end()
+		drift = checkDrift(ctx, opts, *first)
+	}
+
+	results = withSummaries(results)
+
+	return results, drift, nil
+}
+
+// storeQueries returns the query of a benchmark with its own data: rule
+// b.policy of the embedded policy filename, prepared with a store holding
+// b.data. Each benchmark is prepared once.
+func storeQueries(filename string) func(b benchDef) (rego.PreparedEvalQuery, error) {
+	prepared := make(map[string]rego.PreparedEvalQuery)
+	pkg := "data.policy." + strings.TrimSuffix(filename, ".rego") + "."
+	return func(b benchDef) (rego.PreparedEvalQuery, error) {
+		if q, ok := prepared[b.name]; ok {
+			return q, nil
+		}
+		p, err := prepareDataQuery(b.name, filename, pkg+b.policy, b.data)
+		if err != nil {
+			return rego.PreparedEvalQuery{}, err
+		}
+		prepared[b.name] = p.Query
+		return p.Query, nil
+	}
+}
+
+// preparedMap indexes the policies returned by prepare by name.
+func preparedMap(prepare func() ([]PreparedPolicy, error)) (map[string]rego.PreparedEvalQuery, error) {
+	prepared, err := prepare()
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]rego.PreparedEvalQuery, len(prepared))
+	for _, p := range prepared {
+		m[p.Name] = p.Query
+	}
+	return m, nil
 }
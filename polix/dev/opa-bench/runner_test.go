@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4, 5}
+	cases := []struct {
+		p            float64
+		nearestRank  float64
+		interpolated float64
+	}{
+		{0, 1, 1},
+		{0.5, 3, 3},
+		{0.9, 4, 4.6},
+		{1, 5, 5},
+	}
+	for _, c := range cases {
+		if got := percentile(sorted, c.p); got != c.nearestRank {
+			t.Errorf("percentile(%v) = %v, want %v", c.p, got, c.nearestRank)
+		}
+		if got := interpolatedPercentile(sorted, c.p); got != c.interpolated {
+			t.Errorf("interpolatedPercentile(%v) = %v, want %v", c.p, got, c.interpolated)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	cases := []struct {
+		sorted []float64
+		want   []int
+	}{
+		{[]float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 2}},
+		{[]float64{5, 5, 5}, []int{3, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{[]float64{1, 1, 1, 2, 100}, []int{4, 0, 0, 0, 0, 0, 0, 0, 0, 1}},
+	}
+	for _, c := range cases {
+		if got := histogram(c.sorted, 10); !slices.Equal(got, c.want) {
+			t.Errorf("histogram(%v) = %v, want %v", c.sorted, got, c.want)
+		}
+	}
+}
+
+func TestCategorySummaries(t *testing.T) {
+	result := func(name string, mean int64) BenchmarkResult {
+		return BenchmarkResult{Name: name, Results: map[string]interface{}{"mean-ns": mean}}
+	}
+	results := []BenchmarkResult{
+		result("opa/simple-satisfied", 100),
+		result("opa/quantifier/forall-small", 10),
+		result("opa/quantifier/forall-large", 1000),
+		{Name: "opa/count/timed-out", Results: map[string]interface{}{"timed-out": true}},
+		result("opa/simple-denied", 400),
+	}
+	got := categorySummaries(results)
+	want := []struct {
+		name       string
+		mean       int64
+		benchmarks int
+	}{
+		{"opa/_summary", 200, 2},
+		{"opa/quantifier/_summary", 100, 2},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("categorySummaries returned %d results, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.Name != w.name || g.Results["mean-ns"] != w.mean || g.Results["benchmarks"] != w.benchmarks {
+			t.Errorf("summary %d = %s %v, want %s mean-ns %d across %d", i, g.Name, g.Results, w.name, w.mean, w.benchmarks)
+		}
+	}
+}
+
+func TestSampleResultConfidenceInterval(t *testing.T) {
+	samples := []float64{1000, 2000, 3000, 4000, 5000, 6000, 7000, 8000, 9000, 10000}
+	var mem runtime.MemStats
+	r := sampleResult("opa/ci", samples, &mem, &mem, runOptions{})
+	// Sample sd 3027.65 over sqrt(10), times t(9) = 2.262.
+	if r.Results["ci-95-low"] != int64(3334) || r.Results["ci-95-high"] != int64(7665) {
+		t.Errorf("95%% CI = %v-%v, want 3334-7665", r.Results["ci-95-low"], r.Results["ci-95-high"])
+	}
+	if got := r.Results["rel-margin"].(float64); math.Abs(got-39.376) > 0.001 {
+		t.Errorf("rel-margin = %v, want 39.376", got)
+	}
+	if got := tCritical95(999); got != 1.980 {
+		t.Errorf("tCritical95(999) = %v, want 1.980", got)
+	}
+}
+
+func TestAggregateRuns(t *testing.T) {
+	var mem runtime.MemStats
+	opts := runOptions{keepSamples: true}
+	fast := []float64{100, 100, 100, 100, 100, 100, 100, 100, 100, 100}
+	slow := []float64{300, 300, 300, 300, 300, 300, 300, 300, 300, 300}
+	runs := [][]BenchmarkResult{
+		{sampleResult("opa/a", fast, &mem, &mem, opts)},
+		{stoppedResult("opa/a", context.Canceled, 3)},
+		{sampleResult("opa/a", slow, &mem, &mem, opts)},
+	}
+	got := aggregateRuns(runs, opts)[0]
+	if got.Results["runs"] != 2 {
+		t.Errorf("runs = %v, want 2, excluding the interrupted run", got.Results["runs"])
+	}
+	if got.Results["mean-ns"] != int64(200) || got.Results["samples"] != 20 {
+		t.Errorf("mean-ns %v over %v samples, want 200 over 20", got.Results["mean-ns"], got.Results["samples"])
+	}
+	// Pooled, the p99 is the slow run's, not the average of the two p99s.
+	if got.Results["p99-ns"] != int64(300) {
+		t.Errorf("p99-ns = %v, want 300 from the pooled samples", got.Results["p99-ns"])
+	}
+	if got.Results["run-variance"] != int64(100) {
+		t.Errorf("run-variance = %v, want 100", got.Results["run-variance"])
+	}
+}
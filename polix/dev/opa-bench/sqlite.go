@@ -0,0 +1,110 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// sqliteAvailable reports whether a SQLite driver is linked in; see
+// sqlite_driver.go.
+var sqliteAvailable bool
+
+// sqliteSchema creates the tables of the results database. Each run is
+// keyed by its timestamp; its benchmarks keep the headline statistics as
+// columns for querying and every Results value as JSON.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	timestamp         TEXT PRIMARY KEY,
+	engine            TEXT NOT NULL,
+	go_version        TEXT,
+	goos              TEXT,
+	goarch            TEXT,
+	num_cpu           INTEGER,
+	gomaxprocs        INTEGER,
+	cpu_model         TEXT,
+	opa_version       TEXT,
+	seed              INTEGER,
+	drift             REAL,
+	warmup_iterations INTEGER,
+	sample_iterations INTEGER,
+	count             INTEGER
+);
+CREATE TABLE IF NOT EXISTS benchmarks (
+	run_timestamp TEXT NOT NULL REFERENCES runs (timestamp),
+	name          TEXT NOT NULL,
+	engine        TEXT,
+	mean_ns       REAL,
+	median_ns     REAL,
+	std_dev       REAL,
+	cv            REAL,
+	p90_ns        REAL,
+	p95_ns        REAL,
+	p99_ns        REAL,
+	samples       INTEGER,
+	error         TEXT,
+	results       TEXT NOT NULL,
+	PRIMARY KEY (run_timestamp, name)
+);
+`
+
+// nullableResult returns the numeric result key of b, or nil when b has
+// none, for a nullable column.
+func nullableResult(b BenchmarkResult, key string) interface{} {
+	if v, ok := resultValue(b, key); ok {
+		return v
+	}
+	return nil
+}
+
+// writeSQLite adds the run in data to the SQLite database at path,
+// creating the database and its tables on first use, so results can be
+// tracked over time with plain SQL.
+func writeSQLite(path string, data ResultsOutput) error {
+	if !sqliteAvailable {
+		return errors.New("-format=sqlite requires building with -tags opa_sqlite")
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("creating tables in %s: %w", path, err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	m := data.Metadata
+	if _, err := tx.Exec(`INSERT INTO runs VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		data.Timestamp, data.Engine, m.GoVersion, m.GOOS, m.GOARCH, m.NumCPU, m.GOMAXPROCS,
+		m.CPUModel, m.OPAVersion, m.Seed, m.Drift,
+		data.WarmupIterations, data.SampleIterations, data.Count); err != nil {
+		return fmt.Errorf("recording run: %w", err)
+	}
+	for _, b := range data.Benchmarks {
+		results, err := json.Marshal(b.Results)
+		if err != nil {
+			return fmt.Errorf("marshaling %s: %w", b.Name, err)
+		}
+		engine := b.Engine
+		if engine == "" {
+			engine = data.Engine
+		}
+		if _, err := tx.Exec(`INSERT INTO benchmarks VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			data.Timestamp, b.Name, engine,
+			nullableResult(b, "mean-ns"), nullableResult(b, "median-ns"),
+			nullableResult(b, "std-dev"), nullableResult(b, "cv"),
+			nullableResult(b, "p90-ns"), nullableResult(b, "p95-ns"), nullableResult(b, "p99-ns"),
+			nullableResult(b, "samples"), sql.NullString{String: b.Error, Valid: b.Error != ""},
+			string(results)); err != nil {
+			return fmt.Errorf("recording %s: %w", b.Name, err)
+		}
+	}
+	return tx.Commit()
+}
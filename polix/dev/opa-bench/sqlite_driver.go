@@ -0,0 +1,11 @@
+//go:build opa_sqlite
+
+// Building with -tags opa_sqlite links the SQLite driver, which needs cgo,
+// and enables -format=sqlite.
+package main
+
+import _ "github.com/mattn/go-sqlite3"
+
+func init() {
+	sqliteAvailable = true
+}
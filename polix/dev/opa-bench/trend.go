@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// baselineFlag collects the results files of repeated -baseline flags,
+// expanding each value as a glob.
+type baselineFlag []string
+
+func (f *baselineFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *baselineFlag) Set(value string) error {
+	matches, err := filepath.Glob(value)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		// Left for loadResults to report as missing.
+		matches = []string{value}
+	}
+	*f = append(*f, matches...)
+	return nil
+}
+
+// baselineRun is a results file loaded with -baseline.
+type baselineRun struct {
+	path string
+	data ResultsOutput
+	time time.Time
+}
+
+// loadBaselines loads the results files at paths, ordered oldest first by
+// their timestamps.
+func loadBaselines(paths []string) ([]baselineRun, error) {
+	runs := make([]baselineRun, 0, len(paths))
+	for _, path := range paths {
+		data, err := loadResults(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkSchema(path, data); err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(time.RFC3339Nano, data.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("parsing timestamp of baseline %s: %w", path, err)
+		}
+		runs = append(runs, baselineRun{path, data, t})
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].time.Before(runs[j].time) })
+	return runs, nil
+}
+
+// sparkBars are the bar heights of a sparkline, lowest first.
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline draws values as bars scaled between their minimum and
+// maximum. A NaN value, for a run without the benchmark, is a space.
+func sparkline(values []float64) string {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	var sb strings.Builder
+	for _, v := range values {
+		switch {
+		case math.IsNaN(v):
+			sb.WriteRune(' ')
+		case hi == lo:
+			sb.WriteRune(sparkBars[0])
+		default:
+			i := int((v - lo) / (hi - lo) * float64(len(sparkBars)-1))
+			sb.WriteRune(sparkBars[i])
+		}
+	}
+	return sb.String()
+}
+
+// printTrend prints a sparkline of the mean-ns of every benchmark across
+// the baseline runs, oldest first, and the current results, followed by
+// its first and last mean-ns and the change between them. Benchmarks
+// are listed in current order, then those only in a baseline.
+func printTrend(w io.Writer, runs []baselineRun, current []BenchmarkResult) {
+	columns := make([]map[string]float64, 0, len(runs)+1)
+	for _, run := range runs {
+		columns = append(columns, meansByName(run.data.Benchmarks))
+	}
+	columns = append(columns, meansByName(current))
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, b := range current {
+		if !seen[b.Name] {
+			seen[b.Name] = true
+			names = append(names, b.Name)
+		}
+	}
+	for _, run := range runs {
+		for _, b := range run.data.Benchmarks {
+			if !seen[b.Name] {
+				seen[b.Name] = true
+				names = append(names, b.Name)
+			}
+		}
+	}
+
+	width := max(len(columns), len("Trend"))
+	fmt.Fprintf(w, "  %-45s %-*s %12s %12s %9s\n",
+		"Benchmark", width, "Trend", "First ns", "Last ns", "Change")
+	for _, name := range names {
+		values := make([]float64, len(columns))
+		first, last := math.NaN(), math.NaN()
+		for i, means := range columns {
+			v, ok := means[name]
+			if !ok {
+				values[i] = math.NaN()
+				continue
+			}
+			values[i] = v
+			if math.IsNaN(first) {
+				first = v
+			}
+			last = v
+		}
+		if math.IsNaN(first) {
+			continue
+		}
+		change := "-"
+		if first > 0 {
+			change = fmt.Sprintf("%+.1f%%", (last-first)/first*100)
+		}
+		fmt.Fprintf(w, "  %-45s %-*s %12.0f %12.0f %9s\n", name, width, sparkline(values), first, last, change)
+	}
+}
+
+// meansByName indexes the mean-ns of the measured results by name.
+func meansByName(results []BenchmarkResult) map[string]float64 {
+	means := make(map[string]float64, len(results))
+	for _, r := range results {
+		if m, ok := resultValue(r, "mean-ns"); ok {
+			means[r.Name] = m
+		}
+	}
+	return means
+}
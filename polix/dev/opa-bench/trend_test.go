@@ -0,0 +1,22 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSparkline(t *testing.T) {
+	cases := []struct {
+		values []float64
+		want   string
+	}{
+		{[]float64{0, 1, 2, 3, 4, 5, 6, 7}, "▁▂▃▄▅▆▇█"},
+		{[]float64{10, math.NaN(), 20}, "▁ █"},
+		{[]float64{5, 5}, "▁▁"},
+	}
+	for _, c := range cases {
+		if got := sparkline(c.values); got != c.want {
+			t.Errorf("sparkline(%v) = %q, want %q", c.values, got, c.want)
+		}
+	}
+}
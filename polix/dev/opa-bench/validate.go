@@ -0,0 +1,50 @@
+package main
+
+// validatePolicies prepares every policy a run with opts would evaluate:
+// those of opts.policyDir when it is set, otherwise every embedded one.
+// It carries on past a broken policy, so all of them are reported.
+func validatePolicies(opts runOptions) []error {
+	var errs []error
+	if opts.policyDir != "" {
+		defs, err := dirBenchmarks(opts.policyDir, opts.inputPath)
+		if err != nil {
+			return []error{err}
+		}
+		seen := make(map[string]bool)
+		for _, b := range defs {
+			if seen[b.policy] {
+				continue
+			}
+			seen[b.policy] = true
+			if _, err := prepareDirPolicy(opts.policyDir, b); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errs
+	}
+
+	for _, prepare := range []func() ([]PreparedPolicy, error){
+		preparePolicies,
+		prepareQuantifierPolicies,
+		prepareCountFilterPolicies,
+		prepareNestedPolicies,
+		prepareWidePolicies,
+		prepareRegexPolicies,
+	} {
+		if _, err := prepare(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	// The rules read from data compile without it.
+	for _, p := range []struct{ filename, query string }{
+		{"data_lookup.rego", "data.policy.data_lookup.user_active"},
+		{"data_lookup.rego", "data.policy.data_lookup.role_granted"},
+		{"membership.rego", "data.policy.membership.set_member"},
+		{"membership.rego", "data.policy.membership.array_member"},
+	} {
+		if _, err := prepareQuery(p.query, p.filename, p.query); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/v1/rego"
+)
+
+// wasmAvailable reports whether OPA's WASM engine is linked in; see
+// wasm_engine.go.
+var wasmAvailable bool
+
+// prepareWasmQuery compiles the embedded policy filename to WASM and
+// prepares query for evaluation through the WASM runtime.
+func prepareWasmQuery(name string, filename string, query string) (PreparedPolicy, error) {
+	policyBytes, err := policies.ReadFile("policies/" + filename)
+	if err != nil {
+		return PreparedPolicy{}, fmt.Errorf("reading %s: %w", filename, err)
+	}
+
+	prepared, err := rego.New(
+		rego.Query(query),
+		rego.Module(filename, string(policyBytes)),
+		rego.Target("wasm"),
+	).PrepareForEval(context.Background())
+	if err != nil {
+		return PreparedPolicy{}, fmt.Errorf("preparing wasm %s: %w", name, err)
+	}
+
+	return PreparedPolicy{Name: name, Query: prepared}, nil
+}
+
+// wasmBenchmarks mirrors plainBenchmarks under opa/wasm/, evaluating each
+// policy compiled to WASM against the same documents.
+var wasmBenchmarks = func() []benchDef {
+	defs := make([]benchDef, len(plainBenchmarks))
+	for i, b := range plainBenchmarks {
+		b.name = "opa/wasm/" + strings.TrimPrefix(b.name, "opa/")
+		defs[i] = b
+	}
+	return defs
+}()
+
+// runWasmBenchmarks runs wasmBenchmarks and records on each result its
+// speedup over the interpreted benchmark for the same document in
+// interpreted, when that ran: interpreted mean-ns divided by WASM mean-ns.
+func runWasmBenchmarks(ctx context.Context, opts runOptions, interpreted []BenchmarkResult) ([]BenchmarkResult, error) {
+	selected := opts.selected(wasmBenchmarks)
+	if len(selected) == 0 {
+		return nil, nil
+	}
+	if !wasmAvailable {
+		opts.progress.note("Skipping WASM benchmarks: built without -tags opa_wasm")
+		return nil, nil
+	}
+
+	interpretedMean := make(map[string]float64)
+	for _, r := range interpreted {
+		if m, ok := resultValue(r, "mean-ns"); ok {
+			interpretedMean[r.Name] = m
+		}
+	}
+
+	opts.progress.section("WASM benchmarks")
+	wasmMap := make(map[string]rego.PreparedEvalQuery)
+	var results []BenchmarkResult
+	for _, b := range selected {
+		if ctx.Err() != nil {
+			break
+		}
+		if _, ok := wasmMap[b.policy]; !ok {
+			query := b.query
+			if query == "" {
+				query = "data.policy." + b.policy + ".allow"
+			}
+			p, err := prepareWasmQuery(b.policy, b.policy+".rego", query)
+			if err != nil {
+				return nil, err
+			}
+			wasmMap[b.policy] = p.Query
+		}
+		if err := checkDecision(ctx, b, wasmMap[b.policy]); err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return nil, err
+		}
+		opts.started(b.name)
+		result := runBenchmark(ctx, b.name, wasmMap[b.policy], b.doc, opts)
+		base, ok := interpretedMean["opa/"+strings.TrimPrefix(b.name, "opa/wasm/")]
+		if mean, measured := resultValue(result, "mean-ns"); ok && measured {
+			result.Results["speedup"] = base / mean
+		}
+		results = append(results, result)
+		opts.finished(result)
+	}
+	return results, nil
+}
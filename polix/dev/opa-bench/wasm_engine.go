@@ -0,0 +1,11 @@
+//go:build opa_wasm
+
+// Building with -tags opa_wasm links OPA's WASM engine, which depends on
+// wasmtime through cgo, and enables the -wasm benchmarks.
+package main
+
+import _ "github.com/open-policy-agent/opa/v1/features/wasm"
+
+func init() {
+	wasmAvailable = true
+}
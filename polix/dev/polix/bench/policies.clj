@@ -300,6 +300,36 @@
   {:teams (conj (vec (repeat 4 {:members [{:role "lead"}]}))
                 {:members [{:role "dev"} {:role "dev"}]})})
 
+(defn users-failing-at
+  "`n` active users with verified profiles, except the one at index `i`,
+  which is inactive and unverified, so every quantifier over them exits at
+  `i`."
+  [n i]
+  {:users (assoc (vec (repeat n {:active true :role "user" :score 80
+                                 :profile {:verified true}}))
+                 i {:active false :role "user" :score 80
+                    :profile {:verified false}})})
+
+(def doc-users-20-first-unverified
+  "20 users, first unverified (early exit)."
+  (users-failing-at 20 0))
+
+(def doc-users-20-last-unverified
+  "20 users, last unverified (late exit)."
+  (users-failing-at 20 19))
+
+(def doc-users-100-first-inactive
+  "100 users, first inactive (early exit)."
+  (users-failing-at 100 0))
+
+(def doc-users-100-last-inactive
+  "100 users, last inactive (late exit)."
+  (users-failing-at 100 99))
+
+(def doc-users-100-no-admin
+  "100 users, no admins."
+  {:users (vec (repeat 100 {:role "user"}))})
+
 ;;; ---------------------------------------------------------------------------
 ;;; Count Function Policies
 ;;; ---------------------------------------------------------------------------
@@ -429,3 +459,27 @@
                 {:active true
                  :members [{:role "dev" :level 3}
                            {:role "dev" :level 4}]})})
+
+(def doc-users-5-active-one-unverified
+  "5 active users, last unverified."
+  {:users (conj (vec (repeat 4 {:active true :role "user" :score 90
+                                :profile {:verified true}}))
+                {:active true :role "user" :score 90
+                 :profile {:verified false}})})
+
+(def doc-users-5-mostly-inactive
+  "5 users, 2 active with verified profiles, 3 inactive."
+  {:users (into (vec (repeat 2 {:active true :role "user" :score 90
+                                :profile {:verified true}}))
+                (repeat 3 {:active false :role "user" :score 50
+                           :profile {:verified false}}))})
+
+(def doc-users-100-active-low-score
+  "100 active users, none scoring above 80."
+  {:users (vec (repeat 100 {:active true :role "user" :score 50
+                            :profile {:verified true}}))})
+
+(def doc-org-few-members
+  "Organization with fewer than 5 members."
+  {:org {:name "Acme" :members (vec (repeat 4 {:name "User" :level 5}))}
+   :active true})
@@ -29,16 +29,46 @@
 ;;; Benchmark Execution
 ;;; ---------------------------------------------------------------------------
 
+(def ^:dynamic *bench-opts*
+  "Sampling options for [[run-single]], set by [[run-ci]]'s `:samples` and
+  `:warmup`."
+  {})
+
+(def ^:private quick-bench-budget-ns
+  "Total sampling time of criterium's quick-bench: 6 samples of 100ms."
+  6e8)
+
+(defn- criterium-opts
+  "Returns criterium options for `samples` samples that together take as
+  long as quick-bench's default 6, each timing a shorter batch of calls."
+  [samples]
+  (if samples
+    {:samples               samples
+     :target-execution-time (long (/ quick-bench-budget-ns samples))}
+    {}))
+
 (defn run-single
-  "Runs a single benchmark and returns results map."
+  "Runs a single benchmark and returns results map.
+
+  Calls `f` `:warmup` times from [[*bench-opts*]] on top of criterium's own
+  JIT warmup, then takes its `:samples` (quick-bench's 6 by default). Each
+  criterium sample times a batch of calls, so there is no per-call
+  distribution: the results carry the mean, its 95% bootstrap confidence
+  interval, and the 2.5% and 97.5% tail quantiles as `:lower-q` and
+  `:upper-q`, but no median or percentiles."
   [f]
-  (let [results (crit/quick-benchmark* f {})]
-    {:mean-ns   (long (* (first (:mean results)) 1e9))
-     :std-dev   (long (* (Math/sqrt (first (:variance results))) 1e9))
-     :lower-q   (long (* (first (:lower-q results)) 1e9))
-     :upper-q   (long (* (first (:upper-q results)) 1e9))
-     :samples   (:sample-count results)
-     :gc-count  (:gc-count results)}))
+  (let [{:keys [samples warmup]} *bench-opts*]
+    (dotimes [_ (or warmup 0)] (f))
+    (let [results                 (crit/quick-benchmark* f (criterium-opts samples))
+          [mean [ci-low ci-high]] (:mean results)]
+      {:mean-ns    (long (* mean 1e9))
+       :std-dev    (long (* (Math/sqrt (first (:variance results))) 1e9))
+       :lower-q    (long (* (first (:lower-q results)) 1e9))
+       :upper-q    (long (* (first (:upper-q results)) 1e9))
+       :ci-95-low  (long (* ci-low 1e9))
+       :ci-95-high (long (* ci-high 1e9))
+       :samples    (:sample-count results)
+       :gc-count   (:gc-count results)})))
 
 (defmacro bench-fn
   "Benchmarks a form and returns results with name."
@@ -146,8 +176,16 @@
                (forall-checker p/doc-users-5-one-inactive))
      (bench-fn "quantifier/forall-medium-satisfied"
                (forall-nested p/doc-users-20-all-verified))
+     (bench-fn "quantifier/forall-medium-contradicted-early"
+               (forall-nested p/doc-users-20-first-unverified))
+     (bench-fn "quantifier/forall-medium-contradicted-late"
+               (forall-nested p/doc-users-20-last-unverified))
      (bench-fn "quantifier/forall-large-satisfied"
                (forall-checker p/doc-users-100-all-active))
+     (bench-fn "quantifier/forall-large-contradicted-early"
+               (forall-checker p/doc-users-100-first-inactive))
+     (bench-fn "quantifier/forall-large-contradicted-late"
+               (forall-checker p/doc-users-100-last-inactive))
      ;; Exists benchmarks
      (bench-fn "quantifier/exists-small-satisfied"
                (exists-checker p/doc-users-5-first-admin))
@@ -157,6 +195,8 @@
                (exists-checker p/doc-users-100-first-admin))
      (bench-fn "quantifier/exists-large-late-exit"
                (exists-checker p/doc-users-100-last-admin))
+     (bench-fn "quantifier/exists-large-contradicted"
+               (exists-checker p/doc-users-100-no-admin))
      ;; Nested quantifier benchmarks
      (bench-fn "quantifier/nested-satisfied"
                (nested-checker p/doc-teams-all-have-lead))
@@ -177,12 +217,20 @@
                (count-simple-checker {:users (vec (repeat 3 {:active true}))}))
      (bench-fn "count/medium-20-satisfied"
                (count-medium-checker p/doc-users-20-all-verified))
+     (bench-fn "count/medium-20-contradicted"
+               (count-medium-checker {:users (vec (repeat 19 {:active true}))}))
      (bench-fn "count/large-100-satisfied"
                (count-large-checker p/doc-users-100-all-active))
+     (bench-fn "count/large-100-contradicted"
+               (count-large-checker {:users (vec (repeat 99 {:active true}))}))
      (bench-fn "count/nested-path"
                (count-nested-checker p/doc-org-with-members))
+     (bench-fn "count/nested-path-contradicted"
+               (count-nested-checker p/doc-org-few-members))
      (bench-fn "count/with-comparison"
-               (count-compare-checker (assoc p/doc-users-5-all-active :active true)))]))
+               (count-compare-checker (assoc p/doc-users-5-all-active :active true)))
+     (bench-fn "count/with-comparison-contradicted"
+               (count-compare-checker (assoc p/doc-users-5-all-active :active false)))]))
 
 (defn filtered-binding-benchmarks
   "Runs filtered binding benchmarks."
@@ -195,6 +243,8 @@
     [;; Forall with filter
      (bench-fn "filtered/forall-small-satisfied"
                (forall-filtered-checker p/doc-users-5-all-active-verified))
+     (bench-fn "filtered/forall-small-contradicted"
+               (forall-filtered-checker p/doc-users-5-active-one-unverified))
      (bench-fn "filtered/forall-small-mixed"
                (forall-filtered-checker p/doc-users-5-mixed-active))
      (bench-fn "filtered/forall-medium"
@@ -213,12 +263,16 @@
      ;; Count with filter
      (bench-fn "filtered/count-simple"
                (count-filtered-checker p/doc-users-5-mixed-active))
+     (bench-fn "filtered/count-simple-contradicted"
+               (count-filtered-checker p/doc-users-5-mostly-inactive))
      (bench-fn "filtered/count-medium"
                (count-filtered-checker p/doc-users-20-half-active))
      (bench-fn "filtered/count-large"
                (count-filtered-checker p/doc-users-100-mostly-active))
      (bench-fn "filtered/count-complex"
                (count-complex-checker p/doc-users-100-mostly-active))
+     (bench-fn "filtered/count-complex-contradicted"
+               (count-complex-checker p/doc-users-100-active-low-score))
      ;; Nested with filter
      (bench-fn "filtered/nested-satisfied"
                (nested-filtered-checker p/doc-teams-5-active-with-leads))
@@ -438,13 +492,16 @@
    Options:
    - `:output` - JSON output file path (default: \"benchmark-results.json\")
    - `:baseline` - Baseline file for regression detection (optional)
-   - `:threshold` - Regression threshold as decimal (default: 0.1 = 10%)"
-  [{:keys [output baseline threshold]
+   - `:threshold` - Regression threshold as decimal (default: 0.1 = 10%)
+   - `:samples` - criterium samples per benchmark (default: quick-bench's 6)
+   - `:warmup` - calls before each benchmark on top of criterium's warmup"
+  [{:keys [output baseline threshold samples warmup]
     :or {output "benchmark-results.json"
          threshold 0.1}}]
   (println "Polix Benchmark Runner")
   (println "======================")
-  (let [benchmarks (run-all-benchmarks)
+  (let [benchmarks (binding [*bench-opts* {:samples samples :warmup warmup}]
+                     (doall (run-all-benchmarks)))
         result     {:timestamp  (.toString (Instant/now))
                     :benchmarks (vec benchmarks)}]
     (spit output (json/generate-string result {:pretty true}))
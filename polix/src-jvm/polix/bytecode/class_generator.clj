@@ -805,6 +805,7 @@
   - All operators are built-in (no custom operators)
   - At least one path with constraints OR bytecode-eligible complex nodes
   - All paths are non-empty vectors
+  - `:in`/`:not-in` operands are sets (not bloom filters)
   - If complex nodes present, they must all be bytecode-eligible
     (simple quantifiers with builtin constraints in body)"
   [constraint-set]
@@ -816,6 +817,9 @@
     (and
      ;; No custom operators
      (not (:has-custom-ops analysis))
+     ;; Set operands are embedded as IPersistentSet fields
+     (every? (fn [c] (or (not (#{:in :not-in} (:op c))) (set? (:value c))))
+             (mapcat second paths))
      ;; Either has simple paths or all complex entries are eligible
      (or has-simple-paths
          (and (:has-complex analysis) complex-eligible))
@@ -0,0 +1,173 @@
+(ns polix.protobuf
+  "Protobuf messages as policy documents.
+
+  [[message->document]] wraps a decoded message in a read-only map view so it
+  can be passed straight to [[polix.unify/unify]] or a compiled policy,
+  without first converting the whole message to Clojure data. Fields are
+  read through the message's generated accessors when a policy looks them
+  up; fields the policy never touches are never converted.
+
+  Requires `com.google.protobuf/protobuf-java` on the classpath. Works with
+  generated message classes and `DynamicMessage` alike.
+
+  ## Field Resolution
+
+  - `:doc/user_id` and `:doc/user-id` both read the proto field `user_id`
+  - enum fields resolve to the enum value's symbolic name, e.g. `\"ACTIVE\"`
+  - nested messages resolve to nested document views, so `:doc/user.role`
+    reads `user.role`
+  - repeated fields resolve to vectors (of document views for message
+    elements), usable as quantifier collections
+  - map fields resolve to Clojure maps
+
+  ## Unset Fields
+
+  Fields with presence (proto2 `optional`, proto3 `optional`, message
+  fields, and `oneof` members) that are not set are absent from the view:
+  `get` returns nil and `contains?` is false, so policies see them as
+  missing data and return an open residual rather than a conflict. Only the
+  set member of a `oneof` is present. Proto3 scalar fields without presence
+  are always present with their default value, and repeated fields are
+  always present, possibly empty."
+  (:require
+   [clojure.string :as str])
+  (:import
+   (clojure.lang IFn IHashEq ILookup IMeta IObj IPersistentMap MapEntry)
+   (com.google.protobuf Descriptors$Descriptor Descriptors$EnumValueDescriptor
+                        Descriptors$FieldDescriptor Descriptors$FieldDescriptor$JavaType
+                        MessageOrBuilder)))
+
+(declare message->document)
+
+(defn- field-for
+  "Returns the field descriptor named by keyword `k`, accepting kebab-case."
+  ^Descriptors$FieldDescriptor [^Descriptors$Descriptor descriptor k]
+  (when (keyword? k)
+    (let [field-name (name k)]
+      (or (.findFieldByName descriptor field-name)
+          (.findFieldByName descriptor (str/replace field-name \- \_))))))
+
+(defn- present?
+  [^MessageOrBuilder msg ^Descriptors$FieldDescriptor field]
+  (or (.isRepeated field)
+      (not (.hasPresence field))
+      (.hasField msg field)))
+
+(defn- convert-value
+  [^Descriptors$FieldDescriptor field v]
+  (condp = (.getJavaType field)
+    Descriptors$FieldDescriptor$JavaType/ENUM
+    (.getName ^Descriptors$EnumValueDescriptor v)
+
+    Descriptors$FieldDescriptor$JavaType/MESSAGE
+    (message->document v)
+
+    v))
+
+(defn- field-value
+  [^MessageOrBuilder msg ^Descriptors$FieldDescriptor field]
+  (let [raw (.getField msg field)]
+    (cond
+      (.isMapField field)
+      (into {}
+            (map (fn [entry]
+                   (let [entry-doc (message->document entry)]
+                     [(get entry-doc :key) (get entry-doc :value)])))
+            raw)
+
+      (.isRepeated field)
+      (mapv #(convert-value field %) raw)
+
+      :else
+      (convert-value field raw))))
+
+(defn- present-fields
+  [^MessageOrBuilder msg]
+  (filter #(present? msg %) (.getFields (.getDescriptorForType msg))))
+
+(defn- realize
+  "Converts the present fields of `msg` to a persistent map."
+  [^MessageOrBuilder msg]
+  (into {}
+        (map (fn [^Descriptors$FieldDescriptor field]
+               [(keyword (.getName field)) (field-value msg field)]))
+        (present-fields msg)))
+
+(deftype MessageDocument [^MessageOrBuilder msg meta-map]
+  ILookup
+  (valAt [this k]
+    (.valAt this k nil))
+  (valAt [_ k not-found]
+    (let [field (field-for (.getDescriptorForType msg) k)]
+      (if (and field (present? msg field))
+        (field-value msg field)
+        not-found)))
+
+  IPersistentMap
+  (containsKey [_ k]
+    (let [field (field-for (.getDescriptorForType msg) k)]
+      (boolean (and field (present? msg field)))))
+  (entryAt [this k]
+    (when (.containsKey this k)
+      (MapEntry/create k (.valAt this k))))
+  (assoc [_ k v]
+    (assoc (realize msg) k v))
+  (assocEx [_ k v]
+    (.assocEx ^IPersistentMap (realize msg) k v))
+  (without [_ k]
+    (dissoc (realize msg) k))
+  (count [_]
+    (count (present-fields msg)))
+  (cons [_ o]
+    (conj (realize msg) o))
+  (empty [_]
+    {})
+  (equiv [_ o]
+    (= (realize msg) o))
+  (seq [_]
+    (seq (realize msg)))
+  (iterator [_]
+    (.iterator ^Iterable (realize msg)))
+
+  IHashEq
+  (hasheq [_]
+    (hash (realize msg)))
+
+  IFn
+  (invoke [this k]
+    (.valAt this k nil))
+  (invoke [this k not-found]
+    (.valAt this k not-found))
+
+  IMeta
+  (meta [_] meta-map)
+
+  IObj
+  (withMeta [_ m]
+    (MessageDocument. msg m))
+
+  Object
+  (equals [_ o]
+    (= (realize msg) o))
+  (hashCode [_]
+    (.hashCode ^Object (realize msg)))
+  (toString [_]
+    (str (realize msg))))
+
+(defn message->document
+  "Returns a read-only map view of protobuf message `msg` for use as a
+  policy document.
+
+  Field values are resolved on lookup as described in the namespace
+  docstring. Updating the view (`assoc`, `dissoc`, `conj`) returns a plain
+  Clojure map of the present fields with the change applied.
+
+      (unify [:= :doc/status \"ACTIVE\"] (message->document event))
+      ;=> {}"
+  [^MessageOrBuilder msg]
+  (MessageDocument. msg nil))
+
+(defn message-document?
+  "Returns true if `x` is a document view created by [[message->document]]."
+  [x]
+  (instance? MessageDocument x))
@@ -0,0 +1,51 @@
+(ns polix.async-test
+  (:require
+   [clojure.core.async :as async]
+   [clojure.test :refer [deftest is testing]]
+   [polix.async :as pasync]
+   [polix.compiler :as compiler]))
+
+(def ^:private check
+  (compiler/compile-policies [[:= :doc/role "admin"] [:> :doc/level 5]]))
+
+(defn- run
+  ([check docs] (run check docs {}))
+  ([check docs opts]
+   (let [in (async/to-chan! docs)]
+     (async/<!! (async/into [] (pasync/evaluate-channel check in (async/chan 4) opts))))))
+
+(deftest evaluate-channel-test
+  (let [docs [{:role "admin" :level 10}
+              {:role "guest" :level 10}
+              {:role "admin"}]]
+    (testing "emits a result per document in input order"
+      (let [outputs (run check docs)]
+        (is (= docs (map :doc outputs)))
+        (is (= {} (:result (first outputs))))
+        (is (= {[:level] [[:> 5]]} (:result (nth outputs 2))))))
+
+    (testing "order is preserved with parallel evaluation"
+      (let [many (map (fn [n] {:role "admin" :level n}) (range 50))]
+        (is (= many (map :doc (run check many {:parallelism 4}))))))
+
+    (testing "passing-only? drops unsatisfied documents"
+      (is (= [{:role "admin" :level 10}]
+             (map :doc (run check docs {:passing-only? true})))))))
+
+(deftest evaluate-channel-errors-test
+  (let [failing (fn [doc]
+                  (if (:boom doc)
+                    (throw (ex-info "boom" {}))
+                    {}))
+        outputs (run failing [{:boom true} {:ok true}] {:passing-only? true})]
+    (testing "errors are emitted as sentinels, not dropped"
+      (is (= 2 (count outputs)))
+      (is (pasync/error? (first outputs)))
+      (is (= "boom" (ex-message (:error (first outputs))))))))
+
+(deftest evaluate-channel-close-test
+  (testing "output stays open when close? is false"
+    (let [out (async/chan 4)]
+      (pasync/evaluate-channel check (async/to-chan! [{:role "admin" :level 10}]) out {:close? false})
+      (is (some? (async/<!! out)))
+      (is (= :open (async/alt!! out :closed (async/timeout 50) :open))))))
@@ -0,0 +1,138 @@
+(ns polix.protobuf-test
+  (:require
+   [clojure.test :refer [deftest is testing]]
+   [polix.compiler :as compiler]
+   [polix.protobuf :as pb]
+   [polix.unify :as unify])
+  (:import
+   (com.google.protobuf DescriptorProtos$DescriptorProto DescriptorProtos$EnumDescriptorProto
+                        DescriptorProtos$EnumValueDescriptorProto
+                        DescriptorProtos$FieldDescriptorProto
+                        DescriptorProtos$FieldDescriptorProto$Label
+                        DescriptorProtos$FieldDescriptorProto$Type
+                        DescriptorProtos$FileDescriptorProto DescriptorProtos$OneofDescriptorProto
+                        Descriptors$Descriptor Descriptors$FileDescriptor DynamicMessage)))
+
+;;; ---------------------------------------------------------------------------
+;;; Test Schema
+;;; ---------------------------------------------------------------------------
+
+(defn- field-proto
+  [field-name number type & {:keys [repeated? type-name oneof]}]
+  (cond-> (-> (DescriptorProtos$FieldDescriptorProto/newBuilder)
+              (.setName field-name)
+              (.setNumber (int number))
+              (.setType type)
+              (.setLabel (if repeated?
+                           DescriptorProtos$FieldDescriptorProto$Label/LABEL_REPEATED
+                           DescriptorProtos$FieldDescriptorProto$Label/LABEL_OPTIONAL)))
+    type-name (.setTypeName type-name)
+    oneof     (.setOneofIndex (int oneof))
+    true      (.build)))
+
+(def ^:private file-descriptor
+  (let [status (-> (DescriptorProtos$EnumDescriptorProto/newBuilder)
+                   (.setName "Status")
+                   (.addValue (-> (DescriptorProtos$EnumValueDescriptorProto/newBuilder)
+                                  (.setName "UNKNOWN") (.setNumber 0) (.build)))
+                   (.addValue (-> (DescriptorProtos$EnumValueDescriptorProto/newBuilder)
+                                  (.setName "ACTIVE") (.setNumber 1) (.build)))
+                   (.build))
+        item   (-> (DescriptorProtos$DescriptorProto/newBuilder)
+                   (.setName "Item")
+                   (.addField (field-proto "sku" 1 DescriptorProtos$FieldDescriptorProto$Type/TYPE_STRING))
+                   (.addField (field-proto "qty" 2 DescriptorProtos$FieldDescriptorProto$Type/TYPE_INT32))
+                   (.build))
+        event  (-> (DescriptorProtos$DescriptorProto/newBuilder)
+                   (.setName "Event")
+                   (.addField (field-proto "actor_role" 1 DescriptorProtos$FieldDescriptorProto$Type/TYPE_STRING))
+                   (.addField (field-proto "status" 2 DescriptorProtos$FieldDescriptorProto$Type/TYPE_ENUM
+                                           :type-name ".test.Status"))
+                   (.addField (field-proto "items" 3 DescriptorProtos$FieldDescriptorProto$Type/TYPE_MESSAGE
+                                           :type-name ".test.Item" :repeated? true))
+                   (.addField (field-proto "priority" 4 DescriptorProtos$FieldDescriptorProto$Type/TYPE_INT32))
+                   (.addField (field-proto "user_id" 5 DescriptorProtos$FieldDescriptorProto$Type/TYPE_STRING
+                                           :oneof 0))
+                   (.addField (field-proto "group_id" 6 DescriptorProtos$FieldDescriptorProto$Type/TYPE_STRING
+                                           :oneof 0))
+                   (.addOneofDecl (-> (DescriptorProtos$OneofDescriptorProto/newBuilder)
+                                      (.setName "target")
+                                      (.build)))
+                   (.build))]
+    (Descriptors$FileDescriptor/buildFrom
+     (-> (DescriptorProtos$FileDescriptorProto/newBuilder)
+         (.setName "test.proto")
+         (.setPackage "test")
+         (.setSyntax "proto2")
+         (.addEnumType status)
+         (.addMessageType item)
+         (.addMessageType event)
+         (.build))
+     (make-array Descriptors$FileDescriptor 0))))
+
+(defn- message
+  [type-name fields]
+  (let [^Descriptors$Descriptor descriptor (.findMessageTypeByName ^Descriptors$FileDescriptor file-descriptor type-name)
+        builder                            (DynamicMessage/newBuilder descriptor)]
+    (doseq [[field-name value] fields
+            :let               [field (.findFieldByName descriptor field-name)]]
+      (if (sequential? value)
+        (doseq [v value] (.addRepeatedField builder field v))
+        (.setField builder field value)))
+    (.build builder)))
+
+(def ^:private event
+  (message "Event"
+           {"actor_role" "admin"
+            "status"     (.findValueByName (.findEnumTypeByName ^Descriptors$FileDescriptor file-descriptor "Status")
+                                           "ACTIVE")
+            "items"      [(message "Item" {"sku" "a" "qty" (int 2)})
+                          (message "Item" {"sku" "b" "qty" (int 5)})]
+            "user_id"    "u-1"}))
+
+;;; ---------------------------------------------------------------------------
+;;; Tests
+;;; ---------------------------------------------------------------------------
+
+(deftest message-document-lookup-test
+  (let [doc (pb/message->document event)]
+    (testing "fields resolve by proto name or kebab-case"
+      (is (= "admin" (get doc :actor_role)))
+      (is (= "admin" (get doc :actor-role))))
+
+    (testing "enum fields resolve to symbolic names"
+      (is (= "ACTIVE" (get doc :status))))
+
+    (testing "repeated message fields resolve to vectors of documents"
+      (is (= 2 (count (get doc :items))))
+      (is (= "b" (get-in doc [:items 1 :sku]))))
+
+    (testing "unset optional fields are absent"
+      (is (not (contains? doc :priority)))
+      (is (nil? (get doc :priority))))
+
+    (testing "only the set oneof member is present"
+      (is (= "u-1" (get doc :user_id)))
+      (is (not (contains? doc :group_id))))
+
+    (testing "updates produce plain maps"
+      (is (= "x" (:priority (assoc doc :priority "x"))))
+      (is (not (pb/message-document? (assoc doc :priority 1)))))))
+
+(deftest message-document-policy-test
+  (let [doc (pb/message->document event)]
+    (testing "unification reads fields directly"
+      (is (= {} (unify/unify [:and [:= :doc/actor-role "admin"]
+                              [:= :doc/status "ACTIVE"]]
+                             doc))))
+
+    (testing "repeated fields work with quantifiers"
+      (is (= {} (unify/unify [:forall [i :doc/items] [:> :i/qty 0]] doc))))
+
+    (testing "unset fields yield open residuals"
+      (is (= {[:priority] [[:> 3]]} (unify/unify [:> :doc/priority 3] doc))))
+
+    (testing "compiled policies accept message documents"
+      (let [check (compiler/compile-policies [[:= :doc/actor-role "admin"]
+                                              [:in :doc/status #{"ACTIVE"}]])]
+        (is (= {} (check doc)))))))